@@ -3,15 +3,23 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/parser"
+	"go/token"
 	"io"
 	"log"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"slices"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -22,20 +30,48 @@ type GovanishContext struct {
 	FuncRegistry  FuncRegistry
 }
 
-func LoadPackage(dir string) ([]*packages.Package, error) {
+func LoadPackage(dir string, buildFlags ...string) ([]*packages.Package, error) {
+	return LoadPackages(dir, []string{"./..."}, buildFlags...)
+}
+
+func LoadPackages(dir string, patterns []string, buildFlags ...string) ([]*packages.Package, error) {
+	sources := &sync.Map{}
 	cfg := &packages.Config{
-		Mode:  packages.NeedSyntax | packages.NeedFiles | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports,
-		Tests: false,
-		Dir:   dir,
+		Mode:       packages.NeedName | packages.NeedSyntax | packages.NeedFiles | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports,
+		Tests:      false,
+		Dir:        dir,
+		BuildFlags: buildFlags,
+		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			sources.Store(filename, src)
+			return parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+		},
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
 	}
+	loadedSources.Store(sources)
+	return pkgs, nil
+}
+
+// loadedSources keeps content of the files parsed by the latest LoadPackages call (filename -> []byte),
+// so offsets of the nodes always point to the same content even if file changed after the analysis
+// every load replaces the whole map, so long-running modes (lsp, -watch) don't keep content of old or removed files
+var loadedSources atomic.Pointer[sync.Map]
 
-	return packages.Load(cfg, "./...")
+func LoadedSource(filename string) ([]byte, error) {
+	if sources := loadedSources.Load(); sources != nil {
+		if src, ok := sources.Load(filename); ok {
+			return src.([]byte), nil
+		}
+	}
+	return os.ReadFile(filename)
 }
 
 type AssemblyLines map[string][]int
 
 func (assemblyLines AssemblyLines) Normalize() {
-	log.Printf("ready to normalize assembly lines (size %v)", len(assemblyLines))
 	for fileName, lineNumbers := range assemblyLines {
 		sort.Slice(lineNumbers, func(i, j int) bool { return lineNumbers[i] < lineNumbers[j] })
 		deduplicated := make([]int, 0)
@@ -48,34 +84,72 @@ func (assemblyLines AssemblyLines) Normalize() {
 	}
 }
 
-func ParseAssemblyOutput(path string, scanner *bufio.Scanner) AssemblyLines {
-	log.Printf("ready to parse assembly output")
-	assemblyLines := make(AssemblyLines)
-	for scanner.Scan() {
-		line := scanner.Text()
-		cwdIndex := strings.Index(line, path)
-		if cwdIndex == -1 {
-			continue
+func (assemblyLines AssemblyLines) Merge(other AssemblyLines) {
+	for fileName, lineNumbers := range other {
+		assemblyLines[fileName] = append(assemblyLines[fileName], lineNumbers...)
+	}
+}
+
+// InstructionCounts is the number of instructions referencing every source line: file -> line -> count
+type InstructionCounts map[string]map[int]int
+
+func (counts InstructionCounts) Add(fileName string, lineNumber int, count int) {
+	if counts[fileName] == nil {
+		counts[fileName] = make(map[int]int)
+	}
+	counts[fileName][lineNumber] += count
+}
+
+func (counts InstructionCounts) Merge(other InstructionCounts) {
+	for fileName, lines := range other {
+		for lineNumber, count := range lines {
+			counts.Add(fileName, lineNumber, count)
 		}
-		lineRefEnd := strings.Index(line[cwdIndex:], "\t")
-		if lineRefEnd == -1 {
-			panic(fmt.Errorf("unexpected line structure: %v", line))
+	}
+}
+
+// Assembly is the compiler output reduced to the source lines referenced by instructions and function symbols
+type Assembly struct {
+	Lines        AssemblyLines
+	Functions    AssemblyLines     // entry positions of the function symbols: function without entry was never compiled on its own
+	Instructions InstructionCounts // summed over all instantiations and build variants of the code
+}
+
+func NewAssembly() Assembly {
+	return Assembly{Lines: make(AssemblyLines), Functions: make(AssemblyLines), Instructions: make(InstructionCounts)}
+}
+
+func (a Assembly) Normalize() {
+	a.Lines.Normalize()
+	a.Functions.Normalize()
+}
+
+func (a Assembly) Merge(other Assembly) {
+	a.Lines.Merge(other.Lines)
+	a.Functions.Merge(other.Functions)
+	a.Instructions.Merge(other.Instructions)
+}
+
+// InDir returns normalized copy of the assembly restricted to the files of the directory
+func (a Assembly) InDir(dir string) Assembly {
+	subset := NewAssembly()
+	for fileName, lineNumbers := range a.Lines {
+		if filepath.Dir(fileName) == dir {
+			subset.Lines[fileName] = append(subset.Lines[fileName], lineNumbers...)
 		}
-		fileRefEnd := strings.Index(line[cwdIndex:], ":")
-		if fileRefEnd == -1 {
-			panic(fmt.Errorf("unexpected line structure: %v", line))
+	}
+	for fileName, lineNumbers := range a.Functions {
+		if filepath.Dir(fileName) == dir {
+			subset.Functions[fileName] = append(subset.Functions[fileName], lineNumbers...)
 		}
-		fileRef := line[cwdIndex : cwdIndex+fileRefEnd]
-		lineRef := line[cwdIndex+fileRefEnd+1 : cwdIndex+lineRefEnd-1]
-		lineNumber, err := strconv.Atoi(strings.Trim(lineRef, ")]"))
-		if err != nil {
-			panic(fmt.Errorf("unexpected line structure: %v, err=%w", line, err))
+	}
+	for fileName, lines := range a.Instructions {
+		if filepath.Dir(fileName) == dir {
+			subset.Instructions.Merge(InstructionCounts{fileName: lines})
 		}
-		assemblyLines[fileRef] = append(assemblyLines[fileRef], lineNumber)
 	}
-
-	assemblyLines.Normalize()
-	return assemblyLines
+	subset.Normalize()
+	return subset
 }
 
 type TruncateWriter struct {
@@ -92,53 +166,392 @@ func (w *TruncateWriter) Write(buf []byte) (int, error) {
 	return n, err
 }
 
+// BuildConfig describes the go toolchain and flags used to compile the module for assembly inspection
+type BuildConfig struct {
+	GoBinary string       // go command to run, "go" from PATH if empty
+	Env      []string     // extra environment variables for the go command
+	Flags    []string     // build flags forwarded to go build and go list (-tags, -race, -mod=vendor, ...)
+	Gcflags  []string     // user -gcflags values, -S flag is merged into them
+	Replaced []ModuleRoot // local replacements of the dependencies analyzed together with the main module
+}
+
+// Patterns returns package patterns of the main module and all analyzed replaced modules
+func (c BuildConfig) Patterns() []string {
+	patterns := []string{"./..."}
+	for _, module := range c.Replaced {
+		patterns = append(patterns, module.Path+"/...")
+	}
+	return patterns
+}
+
+// ListLocalReplacements returns dependencies replaced with local directories in go.mod of the module
+func ListLocalReplacements(path string, config BuildConfig) ([]ModuleRoot, error) {
+	cmd := config.Command("mod", "edit", "-json")
+	cmd.Dir = path
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(`go mod edit failed: err=%w, cmd="%v", stderr=%v`, err, cmd, strings.TrimSpace(stderr.String()))
+	}
+	var goMod struct {
+		Replace []struct {
+			Old, New struct{ Path, Version string }
+		}
+	}
+	if err := json.Unmarshal(output, &goMod); err != nil {
+		return nil, fmt.Errorf("unable to decode go.mod: %w", err)
+	}
+	var replaced []ModuleRoot
+	for _, replace := range goMod.Replace {
+		// local replacements has no version and their path is a directory path (./local, ../local or absolute path)
+		if replace.New.Version != "" || !(filepath.IsAbs(replace.New.Path) || strings.HasPrefix(replace.New.Path, ".")) {
+			continue
+		}
+		dir := replace.New.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(path, dir)
+		}
+		replaced = append(replaced, ModuleRoot{Path: replace.Old.Path, Dir: filepath.Clean(dir), Replaced: true})
+	}
+	return replaced, nil
+}
+
+// MergeGcflags adds -S to the compiler flags of the module packages while preserving user -gcflags values
+// go command applies the last -gcflags value which pattern matches the package, so -S must be present in every such value
+func MergeGcflags(gcflags []string, modules []ModuleRoot) []string {
+	// -gcflags without package pattern applies only to the packages listed in the command line
+	merged := []string{"-S"}
+	for _, value := range gcflags {
+		pattern, flags := "", value
+		if i := strings.Index(value, "="); i >= 0 && !strings.HasPrefix(value, "-") {
+			pattern, flags = value[:i], value[i+1:]
+		}
+		switch {
+		case pattern == "":
+			merged = append(merged, strings.TrimSpace(flags+" -S"))
+		case pattern == "all":
+			// don't dump assembly of the whole standard library - override value only for the module packages
+			merged = append(merged, value)
+			for _, module := range modules {
+				merged = append(merged, module.Path+"/...="+strings.TrimSpace(flags+" -S"))
+			}
+		case matchesModule(pattern, modules):
+			merged = append(merged, pattern+"="+strings.TrimSpace(flags+" -S"))
+		default:
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
+
+func matchesModule(pattern string, modules []ModuleRoot) bool {
+	if strings.HasPrefix(pattern, ".") {
+		return true
+	}
+	prefix, wildcard := strings.CutSuffix(pattern, "...")
+	for _, module := range modules {
+		if pattern == module.Path || strings.HasPrefix(pattern, module.Path+"/") {
+			return true
+		}
+		if wildcard && strings.HasPrefix(module.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c BuildConfig) BuildArgs(path string, modules []ModuleRoot, packages ...string) []string {
+	args := append([]string{"build", "-C", path, "-o", os.DevNull}, c.Flags...)
+	for _, gcflags := range MergeGcflags(c.Gcflags, modules) {
+		args = append(args, "-gcflags", gcflags)
+	}
+	return append(args, packages...)
+}
+
+func (c BuildConfig) Command(args ...string) *exec.Cmd {
+	goBinary := c.GoBinary
+	if goBinary == "" {
+		goBinary = "go"
+	}
+	cmd := exec.Command(goBinary, args...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	return cmd
+}
+
 func AnalyzeModuleAssembly(path string) (AssemblyLines, error) {
+	return AnalyzeModuleAssemblyWithConfig(path, BuildConfig{})
+}
+
+func AnalyzeModuleAssemblyWithConfig(path string, config BuildConfig) (AssemblyLines, error) {
 	log.Printf("ready to compile project at path '%v' for assembly inspection", path)
-	cmd := exec.Command("go", "build", "-C", path, "-gcflags", "-S", "./...")
-	errs := make(chan error)
-	go func() {
-		defer close(errs)
-		if err := cmd.Start(); err != nil {
-			errs <- err
-			return
+	modules, err := ListModuleRoots(path, config)
+	if err != nil {
+		return nil, err
+	}
+	assembly, err := compileAssembly(path, modules, config, config.Patterns()...)
+	log.Printf("parsed assembly output (size %v)", len(assembly.Lines))
+	warnIfNoAssemblyLines(path, assembly.Lines, err)
+	return assembly.Lines, err
+}
+
+func warnIfNoAssemblyLines(path string, assemblyLines AssemblyLines, err error) {
+	if len(assemblyLines) == 0 && err == nil {
+		log.Printf(
+			"WARNING: compiler output has no instructions referencing files from '%v' - all findings will be missed; "+
+				"check that GOFLAGS and build flags doesn't change positions format in unsupported way",
+			path,
+		)
+	}
+}
+
+// ListModuleRoots returns main module (or all modules of the workspace) with their root directories
+func ListModuleRoots(path string, config BuildConfig) ([]ModuleRoot, error) {
+	cmd := config.Command(append([]string{"list", "-C", path, "-m", "-json"}, config.Flags...)...)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(`go list failed: err=%w, cmd="%v", stderr=%v`, err, cmd, strings.TrimSpace(stderr.String()))
+	}
+	var modules []ModuleRoot
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var module ModuleRoot
+		if err := decoder.Decode(&module); err != nil {
+			return nil, fmt.Errorf("unable to decode go list output: %w", err)
 		}
-		if err := cmd.Wait(); err != nil {
-			errs <- err
+		modules = append(modules, module)
+	}
+	return append(modules, config.Replaced...), nil
+}
+
+// AnalyzeModuleAssemblyParallel compiles every package of the module separately with at most jobs concurrent builds
+// and calls onPackage (never concurrently) as soon as assembly of the package is parsed
+func AnalyzeModuleAssemblyParallel(
+	path string,
+	config BuildConfig,
+	jobs int,
+	cache *AssemblyCache,
+	onPackage func(importPath string, assembly Assembly),
+) (Assembly, error) {
+	pkgs, err := ListModulePackages(path, config)
+	if err != nil {
+		return Assembly{}, err
+	}
+	modules, err := ListModuleRoots(path, config)
+	if err != nil {
+		return Assembly{}, err
+	}
+	keys := cache.PackageKeys(path, pkgs)
+	profiles := DetectPgoProfiles(pkgs)
+	if len(profiles) > 0 && config.PgoMode() == "auto" {
+		log.Printf("detected PGO profiles: %v", profiles)
+	}
+	log.Printf("ready to compile %v packages at path '%v' for assembly inspection (jobs %v)", len(pkgs), path, jobs)
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		assembly = NewAssembly()
+		errs     []error
+		cached   int
+	)
+	// functions inlined into the dependants add lines of the package files to their assembly as well,
+	// so package is streamed only after all module packages which import it are compiled
+	modulePkgs := make(map[string]ModulePackage)
+	for _, pkg := range pkgs {
+		modulePkgs[pkg.ImportPath] = pkg
+	}
+	pendingDependants := make(map[string]int)
+	for _, pkg := range pkgs {
+		for _, dep := range pkg.Deps {
+			if _, ok := modulePkgs[dep]; ok {
+				pendingDependants[dep]++
+			}
+		}
+	}
+	compiled := make(Set)
+	stream := func(importPath string) {
+		if _, ok := compiled[importPath]; !ok || pendingDependants[importPath] > 0 || onPackage == nil {
 			return
 		}
-	}()
+		onPackage(importPath, assembly.InDir(modulePkgs[importPath].Dir))
+	}
+	semaphore := make(chan struct{}, max(jobs, 1))
+	for _, pkg := range pkgs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			pkgAssembly, ok := cache.Load(keys[pkg.ImportPath])
+			var err error
+			if !ok {
+				pkgAssembly, err = compilePackageAssembly(path, modules, PgoVariants(pkg, config, profiles), pkg.ImportPath)
+				if err == nil {
+					cache.Store(keys[pkg.ImportPath], pkgAssembly)
+				}
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if ok {
+				cached++
+			}
+			if err != nil {
+				errs = append(errs, &PackageBuildError{ImportPath: pkg.ImportPath, Err: err})
+			}
+			if len(pkgAssembly.Lines) > 0 {
+				assembly.Merge(pkgAssembly)
+				compiled[pkg.ImportPath] = struct{}{}
+			}
+			stream(pkg.ImportPath)
+			for _, dep := range pkg.Deps {
+				if _, ok := modulePkgs[dep]; ok {
+					pendingDependants[dep]--
+					stream(dep)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assembly.Normalize()
+	log.Printf("parsed assembly output (size %v, cached packages %v)", len(assembly.Lines), cached)
+	warnIfNoAssemblyLines(path, assembly.Lines, errors.Join(errs...))
+	if len(assembly.Lines) == 0 && len(errs) > 0 {
+		return Assembly{}, errors.Join(errs...)
+	}
+	return assembly, errors.Join(errs...)
+}
+
+// PackageBuildError is the compilation error of the single module package
+type PackageBuildError struct {
+	ImportPath string
+	Err        error
+}
+
+func (e *PackageBuildError) Error() string { return fmt.Sprintf("package %v: %v", e.ImportPath, e.Err) }
+func (e *PackageBuildError) Unwrap() error { return e.Err }
+
+// FailedPackages returns build errors of the packages joined into the error of AnalyzeModuleAssemblyParallel
+func FailedPackages(err error) map[string]error {
+	failed := make(map[string]error)
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else if err != nil {
+		errs = []error{err}
+	}
+	for _, err := range errs {
+		var buildErr *PackageBuildError
+		if errors.As(err, &buildErr) {
+			failed[buildErr.ImportPath] = buildErr.Err
+		}
+	}
+	return failed
+}
+
+type ModulePackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	HFiles     []string
+	SFiles     []string
+	EmbedFiles []string
+	Deps       []string
+}
+
+func ListModulePackages(path string, config BuildConfig) ([]ModulePackage, error) {
+	cmd := config.Command(append(append([]string{"list", "-C", path, "-json"}, config.Flags...), config.Patterns()...)...)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(`go list failed: err=%w, cmd="%v", stderr=%v`, err, cmd, strings.TrimSpace(stderr.String()))
+	}
+	var pkgs []ModulePackage
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var pkg ModulePackage
+		if err := decoder.Decode(&pkg); err != nil {
+			return nil, fmt.Errorf("unable to decode go list output: %w", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+func compilePackageAssembly(path string, modules []ModuleRoot, variants []BuildConfig, importPath string) (Assembly, error) {
+	assembly := NewAssembly()
+	var errs []error
+	for _, variant := range variants {
+		variantAssembly, err := compileAssembly(path, modules, variant, importPath)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		assembly.Merge(variantAssembly)
+	}
+	assembly.Normalize()
+	return assembly, errors.Join(errs...)
+}
+
+func compileAssembly(path string, modules []ModuleRoot, config BuildConfig, packages ...string) (Assembly, error) {
+	cmd := config.Command(config.BuildArgs(path, modules, packages...)...)
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return nil, err
+		return Assembly{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return Assembly{}, err
 	}
 	stderrHead := bytes.NewBuffer(nil)
 	stderrTee := io.TeeReader(stderr, &TruncateWriter{writer: stderrHead, limit: 1024})
-	assemblyLines := ParseAssemblyOutput(path, bufio.NewScanner(stderrTee))
-	for err := range errs {
-		if len(assemblyLines) == 0 {
-			return nil, fmt.Errorf(
-				`go build failed: err=%w, cmd="go build -C %v -gcflags -S ./...", stderr=%v`,
+	scanner := bufio.NewScanner(stderrTee)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	assembly := ParseAssembly(path, scanner, modules...)
+	// scanner can stop early on too long lines - drain the pipe in order to not block the compiler
+	_, _ = io.Copy(io.Discard, stderrTee)
+	if err := cmd.Wait(); err != nil {
+		if len(assembly.Lines) == 0 {
+			return Assembly{}, fmt.Errorf(
+				`go build failed: err=%w, cmd="%v", stderr=%v`,
 				err,
-				path,
+				cmd,
 				strings.TrimSpace(stderrHead.String()),
 			)
 		}
-		return assemblyLines, fmt.Errorf(`go build finished with non zero exit code: err=%w`, err)
+		return assembly, fmt.Errorf(`go build finished with non zero exit code: err=%w`, err)
 	}
-	return assemblyLines, nil
+	return assembly, nil
+}
+
+// SourcePosition ignores //line directives and returns position in the compiled file (this is how AssemblyParser keys lines)
+// exception is cgo-rewritten files: they are compiled from temporary copies and only adjusted position points to the package file
+func SourcePosition(pkg *packages.Package, pos token.Pos) token.Position {
+	if position := pkg.Fset.PositionFor(pos, false); slices.Contains(pkg.GoFiles, position.Filename) {
+		return position
+	}
+	return pkg.Fset.Position(pos)
 }
 
 func IsVanished(pkg *packages.Package, assemblyLines AssemblyLines, start, end ast.Node) bool {
-	startPosition, endPosition := pkg.Fset.Position(start.Pos()), pkg.Fset.Position(end.End())
+	startPosition, endPosition := SourcePosition(pkg, start.Pos()), SourcePosition(pkg, end.End())
 	lines, ok := assemblyLines[startPosition.Filename]
 	if !ok {
 		return false
 	}
-	index, _ := slices.BinarySearch(lines, startPosition.Line)
-	if index < len(lines) && lines[index] <= endPosition.Line {
-		return false
-	}
-	return true
+	return !HasLineInRange(lines, startPosition.Line, endPosition.Line)
+}
+
+// HasLineInRange checks that sorted lines contain at least one line from [from, to] range
+func HasLineInRange(lines []int, from, to int) bool {
+	index, _ := slices.BinarySearch(lines, from)
+	return index < len(lines) && lines[index] <= to
 }
 
 func AnalyzeModuleAst(
@@ -146,91 +559,198 @@ func AnalyzeModuleAst(
 	project []*packages.Package,
 	assemblyLines AssemblyLines,
 	funcRegistry FuncRegistry,
+	generated GeneratedFiles,
 	policy AnalysisPolicy,
 	reporting Reporting,
 ) error {
 	log.Printf("ready to analyze module AST")
 	for _, pkg := range project {
-		for _, file := range pkg.Syntax {
-			if ast.IsGenerated(file) {
-				continue
+		AnalyzePackageAst(analysisPath, pkg, assemblyLines, funcRegistry, generated, policy, reporting)
+	}
+	return nil
+}
+
+// GeneratedFiles selects generated files (marked with "Code generated ... DO NOT EDIT." comment) which are analyzed with the rest of the code
+// files are skipped by default because bugs in the generated code usually must be fixed in the generator or template
+type GeneratedFiles struct {
+	All   bool
+	Globs []string // path.Match patterns of the paths relative to the module root; pattern without slash matches file name
+}
+
+// Classify reports whether file is generated and whether analysis must skip it (cgo-rewritten files are not considered generated)
+func (g GeneratedFiles) Classify(analysisPath string, pkg *packages.Package, file *ast.File) (isGenerated, skip bool) {
+	isGenerated = ast.IsGenerated(file) && !IsCgoRewritten(pkg, file)
+	return isGenerated, isGenerated && !g.Includes(analysisPath, SourcePosition(pkg, file.Package).Filename)
+}
+
+func (g GeneratedFiles) Includes(analysisPath, filename string) bool {
+	if g.All {
+		return true
+	}
+	relativePath, err := filepath.Rel(analysisPath, filename)
+	if err != nil {
+		return false
+	}
+	relativePath = filepath.ToSlash(relativePath)
+	for _, glob := range g.Globs {
+		target := relativePath
+		if !strings.Contains(glob, "/") {
+			target = path.Base(relativePath)
+		}
+		if matched, _ := path.Match(glob, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCgoRewritten checks that generated file is the user file rewritten by cgo: its //line directive maps it back to the package source
+func IsCgoRewritten(pkg *packages.Package, file *ast.File) bool {
+	filename := pkg.Fset.Position(file.Package).Filename
+	return filename != pkg.Fset.PositionFor(file.Package, false).Filename && slices.Contains(pkg.GoFiles, filename)
+}
+
+func AnalyzePackageAst(
+	analysisPath string,
+	pkg *packages.Package,
+	assemblyLines AssemblyLines,
+	funcRegistry FuncRegistry,
+	generated GeneratedFiles,
+	policy AnalysisPolicy,
+	reporting Reporting,
+) {
+	for _, file := range pkg.Syntax {
+		isGenerated, skip := generated.Classify(analysisPath, pkg, file)
+		if skip {
+			continue
+		}
+		ctx := GovanishContext{
+			Pkg:           pkg,
+			AssemblyLines: assemblyLines,
+			FuncRegistry:  funcRegistry,
+		}
+		var currentFunc, currentReceiver string
+		guards := make(map[*ast.BlockStmt]guardedBlock)
+		var analyze func(node ast.Node) bool
+		analyze = func(node ast.Node) bool {
+			if funcDecl, ok := node.(*ast.FuncDecl); ok {
+				currentFunc, currentReceiver = funcDecl.Name.Name, FuncReceiver(funcDecl)
 			}
-			ctx := GovanishContext{
-				Pkg:           pkg,
-				AssemblyLines: assemblyLines,
-				FuncRegistry:  funcRegistry,
+			// don't process whole subtree if we should skip the node
+			if policy.ShouldSkip(ctx, node) {
+				return false
 			}
-			var currentFunc string
-			var analyze func(node ast.Node) bool
-			analyze = func(node ast.Node) bool {
-				if funcDecl, ok := node.(*ast.FuncDecl); ok {
-					currentFunc = funcDecl.Name.Name
-				}
-				// don't process whole subtree if we should skip the node
-				if policy.ShouldSkip(ctx, node) {
-					return false
-				}
-				// process subtree for control-flow pivot nodes but skip analysis of the node itself
-				if policy.IsControlFlowPivot(node) {
-					return true
-				}
-				// we can analyze only sequence of statements
-				blockStmt, ok := node.(*ast.BlockStmt)
-				if !ok {
-					return true
-				}
-				previous := -1
-				i := 0
-				for i <= len(blockStmt.List) {
-					skip1 := i < len(blockStmt.List) && policy.ShouldSkip(ctx, blockStmt.List[i])
-					/*
-						- we want to also skip patterns like this:
-						value, err := F()
-						if err != nil {
-							...
-						}
-					*/
-					skip2 := i+1 < len(blockStmt.List) && policy.ShouldSkip(ctx, &ast.BlockStmt{List: blockStmt.List[i : i+2]})
-					pivot := i == len(blockStmt.List) || policy.IsControlFlowPivot(blockStmt.List[i]) || skip1 || skip2
-					// split sequence of statements by pivot positions and analyze regions between them
-					if !pivot {
-						i += 1
-						continue
+			// process subtree for control-flow pivot nodes but skip analysis of the node itself
+			if policy.IsControlFlowPivot(node) {
+				return true
+			}
+			// we can analyze only sequence of statements
+			blockStmt, ok := node.(*ast.BlockStmt)
+			if !ok {
+				return true
+			}
+			previous := -1
+			i := 0
+			for i <= len(blockStmt.List) {
+				skip1 := i < len(blockStmt.List) && policy.ShouldSkip(ctx, blockStmt.List[i])
+				/*
+					- we want to also skip patterns like this:
+					value, err := F()
+					if err != nil {
+						...
 					}
-					if previous+1 < i {
-						region := &ast.BlockStmt{List: blockStmt.List[previous+1 : i]}
-						start, end := blockStmt.List[previous+1], blockStmt.List[i-1]
-						if policy.CheckComplexity(ctx, region) && IsVanished(pkg, assemblyLines, start, end) {
-							reporting.ReportVanished(VanishedInfo{
-								AnalysisPath: analysisPath,
-								Pkg:          pkg,
-								FuncName:     currentFunc,
-								Start:        start,
-								End:          end,
-							})
+				*/
+				skip2 := i+1 < len(blockStmt.List) && policy.ShouldSkip(ctx, &ast.BlockStmt{List: blockStmt.List[i : i+2]})
+				pivot := i == len(blockStmt.List) || policy.IsControlFlowPivot(blockStmt.List[i]) || skip1 || skip2
+				// split sequence of statements by pivot positions and analyze regions between them
+				if !pivot {
+					i += 1
+					continue
+				}
+				if previous+1 < i {
+					region := &ast.BlockStmt{List: blockStmt.List[previous+1 : i]}
+					start, end := blockStmt.List[previous+1], blockStmt.List[i-1]
+					if policy.CheckComplexity(ctx, region) && IsVanished(pkg, assemblyLines, start, end) {
+						var related []RelatedPosition
+						var fixes []SuggestedFix
+						if guard, ok := guards[blockStmt]; ok {
+							if unchecked, ok := FindUncheckedError(pkg, guard.IfStmt, guard.Preceding); ok {
+								related = unchecked.Related()
+								fixes = SuggestDuplicateConditionFix(unchecked)
+							}
 						}
+						reporting.ReportVanished(VanishedInfo{
+							AnalysisPath:   analysisPath,
+							Pkg:            pkg,
+							FuncName:       currentFunc,
+							FuncReceiver:   currentReceiver,
+							Start:          start,
+							End:            end,
+							Generated:      isGenerated,
+							Related:        related,
+							SuggestedFixes: fixes,
+						})
 					}
-					for s := previous + 1; s < i; s++ {
-						ast.Inspect(blockStmt.List[s], analyze)
-					}
-					if skip1 {
-						previous = i
-						i += 1
-					} else if skip2 {
-						previous = i + 1
-						i += 2
-					} else {
-						if i < len(blockStmt.List) {
-							ast.Inspect(blockStmt.List[i], analyze)
+				}
+				for s := previous + 1; s < i; s++ {
+					ast.Inspect(blockStmt.List[s], analyze)
+				}
+				if skip1 {
+					previous = i
+					i += 1
+				} else if skip2 {
+					previous = i + 1
+					i += 2
+				} else {
+					if i < len(blockStmt.List) {
+						if ifStmt, ok := blockStmt.List[i].(*ast.IfStmt); ok {
+							guards[ifStmt.Body] = guardedBlock{IfStmt: ifStmt, Preceding: blockStmt.List[:i]}
 						}
-						previous = i
-						i += 1
+						ast.Inspect(blockStmt.List[i], analyze)
 					}
+					previous = i
+					i += 1
 				}
-				return false
 			}
-			ast.Inspect(file, analyze)
+			return false
+		}
+		ast.Inspect(file, analyze)
+	}
+}
+
+// AnalyzePackageFuncs reports functions and methods without own symbol in the compiler output
+// assembly must be collected from the whole module because generic functions are instantiated in the packages of their callers
+func AnalyzePackageFuncs(analysisPath string, pkg *packages.Package, assembly Assembly, generated GeneratedFiles, reporting Reporting) {
+	// package without any instructions most likely failed to compile - there is nothing to say about its functions
+	if !slices.ContainsFunc(pkg.GoFiles, func(file string) bool { _, ok := assembly.Lines[file]; return ok }) {
+		return
+	}
+	for _, file := range pkg.Syntax {
+		isGenerated, skip := generated.Classify(analysisPath, pkg, file)
+		if skip {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			// blank functions are never compiled by design and functions without body are implemented in assembly
+			if !ok || funcDecl.Body == nil || funcDecl.Name.Name == "_" {
+				continue
+			}
+			start, entry := SourcePosition(pkg, funcDecl.Pos()), SourcePosition(pkg, funcDecl.Body.Lbrace)
+			// compiler emits symbol even for the functions inlined into every caller, so only never compiled functions are left
+			if HasLineInRange(assembly.Functions[start.Filename], start.Line, entry.Line) {
+				continue
+			}
+			reporting.ReportVanished(VanishedInfo{
+				AnalysisPath: analysisPath,
+				Pkg:          pkg,
+				FuncName:     funcDecl.Name.Name,
+				FuncReceiver: FuncReceiver(funcDecl),
+				Start:        funcDecl.Name,
+				End:          funcDecl.Name,
+				Generated:    isGenerated,
+				FuncReason:   FuncNeverCompiled,
+			})
 		}
 	}
-	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestAnalyzeModuleAssembly(t *testing.T) {
@@ -53,6 +54,68 @@ func main() {}`)
 			22, /* main */
 		}, lines)
 	})
+	t.Run("parallel compilation", func(t *testing.T) {
+		dir, dispose, err := MustGenMod(`
+package main
+
+func api(n int) int {
+	if n == 0 {
+		return 1
+	}
+	return n * 2
+}
+
+func main() { println(api(1)) }`)
+		require.Nil(t, err)
+		defer dispose()
+
+		expected, err := AnalyzeModuleAssembly(dir)
+		require.Nil(t, err)
+		streamed := make(AssemblyLines)
+		assembly, err := AnalyzeModuleAssemblyParallel(dir, BuildConfig{}, 2, nil, func(importPath string, assembly Assembly) {
+			require.True(t, strings.HasSuffix(importPath, path.Base(dir)))
+			streamed.Merge(assembly.Lines)
+		})
+		require.Nil(t, err)
+		require.Equal(t, expected, assembly.Lines)
+		require.Equal(t, expected, streamed)
+	})
+	t.Run("package is streamed after dependants", func(t *testing.T) {
+		dir, dispose, err := MustGenMod("package main\n")
+		require.Nil(t, err)
+		defer dispose()
+		src := "package main\n\nimport (\n\t\"os\"\n\n\t\"github.com/sivukhin/govanish/" + path.Base(dir) + "/lib\"\n)\n\nfunc main() { println(lib.Double(len(os.Args))) }\n"
+		require.Nil(t, os.WriteFile(path.Join(dir, "main.go"), []byte(src), 0o644))
+		require.Nil(t, os.MkdirAll(path.Join(dir, "lib"), 0o755))
+		require.Nil(t, os.WriteFile(path.Join(dir, "lib", "lib.go"), []byte("package lib\n\nfunc Double(x int) int { return x * 2 }\n"), 0o644))
+
+		var order []string
+		streamed := make(map[string]Assembly)
+		assembly, err := AnalyzeModuleAssemblyParallel(dir, BuildConfig{}, 2, nil, func(importPath string, assembly Assembly) {
+			order = append(order, path.Base(importPath))
+			streamed[path.Base(importPath)] = assembly
+		})
+		require.Nil(t, err)
+		require.Equal(t, []string{path.Base(dir), "lib"}, order)
+		// instructions of Double inlined into main are streamed together with the lib package
+		require.Equal(t, assembly.InDir(path.Join(dir, "lib")), streamed["lib"])
+	})
+	t.Run("trimpath", func(t *testing.T) {
+		dir, dispose, err := MustGenMod(`
+package main
+
+func main() { println("hello") }`)
+		require.Nil(t, err)
+		defer dispose()
+
+		expected, err := AnalyzeModuleAssembly(dir)
+		require.Nil(t, err)
+		require.Len(t, expected, 1)
+		trimpath := BuildConfig{Env: []string{"GOFLAGS=" + strings.TrimSpace(os.Getenv("GOFLAGS")+" -trimpath")}}
+		assemblyLines, err := AnalyzeModuleAssemblyWithConfig(dir, trimpath)
+		require.Nil(t, err)
+		require.Equal(t, expected, assemblyLines)
+	})
 	t.Run("instantiated generics", func(t *testing.T) {
 		dir, dispose, err := MustGenMod(`
 package main
@@ -109,6 +172,29 @@ func main() {
 	})
 }
 
+func TestMergeGcflags(t *testing.T) {
+	modules := []ModuleRoot{{Path: "example.com/m", Dir: "/m"}}
+	t.Run("no user flags", func(t *testing.T) {
+		require.Equal(t, []string{"-S"}, MergeGcflags(nil, modules))
+	})
+	t.Run("flags without pattern", func(t *testing.T) {
+		require.Equal(t, []string{"-S", "-N -l -S"}, MergeGcflags([]string{"-N -l"}, modules))
+	})
+	t.Run("all pattern", func(t *testing.T) {
+		require.Equal(t, []string{"-S", "all=-N -l", "example.com/m/...=-N -l -S"}, MergeGcflags([]string{"all=-N -l"}, modules))
+	})
+	t.Run("module patterns", func(t *testing.T) {
+		require.Equal(
+			t,
+			[]string{"-S", "example.com/m/pkg=-l -S", "./...=-N -S", "example.com/...=-m -S"},
+			MergeGcflags([]string{"example.com/m/pkg=-l", "./...=-N", "example.com/...=-m"}, modules),
+		)
+	})
+	t.Run("foreign patterns", func(t *testing.T) {
+		require.Equal(t, []string{"-S", "std=-N", "example.com/other=-l"}, MergeGcflags([]string{"std=-N", "example.com/other=-l"}, modules))
+	})
+}
+
 type testPolicy struct {
 	Vanished []simpleVanishedInfo
 }
@@ -122,22 +208,34 @@ func (t *testPolicy) CheckComplexity(ctx GovanishContext, node ast.Node) bool {
 }
 func (t *testPolicy) ReportVanished(info VanishedInfo) {
 	t.Vanished = append(t.Vanished, simpleVanishedInfo{
-		Func:      info.FuncName,
-		StartLine: info.Pkg.Fset.Position(info.Start.Pos()).Line,
-		EndLine:   info.Pkg.Fset.Position(info.End.Pos()).Line,
+		Func:       info.FuncName,
+		StartLine:  info.StartLine(),
+		EndLine:    info.EndLine(),
+		Generated:  info.Generated,
+		FuncReason: info.FuncReason,
 	})
 }
 
 type simpleVanishedInfo struct {
-	Func      string
-	StartLine int
-	EndLine   int
+	Func       string
+	StartLine  int
+	EndLine    int
+	Generated  bool
+	FuncReason FuncVanishReason
 }
 
 func analyze(t *testing.T, src string) []simpleVanishedInfo {
+	return analyzeWithFiles(t, src, nil, GeneratedFiles{})
+}
+
+// analyzeWithFiles puts additional files (like C sources for cgo) near the main.go before analysis
+func analyzeWithFiles(t *testing.T, src string, files map[string]string, generated GeneratedFiles) []simpleVanishedInfo {
 	dir, dispose, err := MustGenMod(src)
 	require.Nil(t, err)
 	defer dispose()
+	for name, content := range files {
+		require.Nil(t, os.WriteFile(path.Join(dir, name), []byte(content), 0o644))
+	}
 
 	assemblyLines, err := AnalyzeModuleAssembly(dir)
 	require.Nil(t, err)
@@ -147,19 +245,133 @@ func analyze(t *testing.T, src string) []simpleVanishedInfo {
 	require.Nil(t, err)
 	funcRegistry := CreateFuncRegistry(project)
 	fmt.Printf("func: %#v\n", funcRegistry)
-	require.Nil(t, AnalyzeModuleAst("path", project, assemblyLines, funcRegistry, policy, policy))
+	require.Nil(t, AnalyzeModuleAst(dir, project, assemblyLines, funcRegistry, generated, policy, policy))
 	return policy.Vanished
 }
 
 var excludeComment = "//go:build exclude\n\n"
 
+// loadExample reads example named after the current subtest
 func loadExample(t *testing.T) string {
 	tokens := strings.Split(t.Name(), "/")
-	data, err := os.ReadFile(path.Join("examples", tokens[len(tokens)-1]))
+	return readExample(t, tokens[len(tokens)-1])
+}
+
+func readExample(t *testing.T, example string) string {
+	data, err := os.ReadFile(path.Join("examples", example))
 	require.Nil(t, err)
 	return strings.TrimPrefix(string(data), excludeComment)
 }
 
+// exampleModule generates temporary module with the example as main.go
+func exampleModule(t *testing.T, example string) (string, func()) {
+	dir, dispose, err := MustGenMod(readExample(t, example))
+	require.Nil(t, err)
+	return dir, dispose
+}
+
+// analyzeModule compiles and analyzes module with the default policy, findings are sent to the reporting
+func analyzeModule(t *testing.T, dir string, reporting Reporting) ([]*packages.Package, Assembly) {
+	assembly, err := AnalyzeModuleAssemblyParallel(dir, BuildConfig{}, 1, nil, nil)
+	require.Nil(t, err)
+	project, err := LoadPackage(dir)
+	require.Nil(t, err)
+	require.Nil(t, AnalyzeModuleAst(dir, project, assembly.Lines, CreateFuncRegistry(project), GeneratedFiles{}, Govanish, reporting))
+	return project, assembly
+}
+
+// reportExample analyzes example with the given reporting and flushes it while module files still exist
+func reportExample(t *testing.T, example string, reporting Reporting) {
+	dir, dispose := exampleModule(t, example)
+	defer dispose()
+	analyzeModule(t, dir, reporting)
+	require.Nil(t, FlushReport(reporting))
+}
+
+// edgeCaseFiles have findings in several files of the main package and in the lib package,
+// snippets contain symbols which must be escaped in XML, JSON and HTML documents
+var edgeCaseFiles = map[string]string{
+	"main.go": `package main
+
+func Quoted(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+	_ = w.Write(2)
+	if err != nil {
+		panic("<" + err.Error() + "> & \"quoted\"")
+	}
+}
+
+func main() {}
+`,
+	"other.go": `package main
+
+func Other(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+	_ = w.Write(2)
+	if err != nil {
+		panic(err)
+	}
+}
+`,
+	"lib/lib.go": `package lib
+
+func Lib(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+	_ = w.Write(2)
+	if err != nil {
+		panic(err)
+	}
+}
+`,
+}
+
+// reportEdgeCases analyzes module with edgeCaseFiles and flushes the reporting while module files still exist
+func reportEdgeCases(t *testing.T, reporting Reporting) {
+	dir, dispose, err := MustGenMod(edgeCaseFiles["main.go"])
+	require.Nil(t, err)
+	defer dispose()
+	for name, src := range edgeCaseFiles {
+		require.Nil(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0o755))
+		require.Nil(t, os.WriteFile(path.Join(dir, name), []byte(src), 0o644))
+	}
+	analyzeModule(t, dir, reporting)
+	require.Nil(t, FlushReport(reporting))
+}
+
+func TestLoadedSource(t *testing.T) {
+	dir, dispose, err := MustGenMod("package main\n\nfunc main() {}\n")
+	require.Nil(t, err)
+	defer dispose()
+	mainPath, extraPath := path.Join(dir, "main.go"), path.Join(dir, "extra.go")
+	require.Nil(t, os.WriteFile(extraPath, []byte("package main\n\nfunc extra() {}\n"), 0o644))
+
+	_, err = LoadPackage(dir)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(mainPath, []byte("package main\n\n// edited\nfunc main() {}\n"), 0o644))
+	content, err := LoadedSource(mainPath)
+	require.Nil(t, err)
+	require.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+
+	// next load replaces sources of the previous one
+	require.Nil(t, os.Remove(extraPath))
+	_, err = LoadPackage(dir)
+	require.Nil(t, err)
+	content, err = LoadedSource(mainPath)
+	require.Nil(t, err)
+	require.Equal(t, "package main\n\n// edited\nfunc main() {}\n", string(content))
+	_, err = LoadedSource(extraPath)
+	require.True(t, os.IsNotExist(err))
+}
+
 func TestAnalysis(t *testing.T) {
 	t.Run("err_not_nil_tricky_bug.go", func(t *testing.T) {
 		vanished := analyze(t, loadExample(t))
@@ -173,6 +385,10 @@ func TestAnalysis(t *testing.T) {
 		vanished := analyze(t, loadExample(t))
 		require.Equal(t, []simpleVanishedInfo{{Func: "NoErrCheck", StartLine: 11, EndLine: 11}}, vanished)
 	})
+	t.Run("duplicate_condition_bug.go", func(t *testing.T) {
+		vanished := analyze(t, loadExample(t))
+		require.Equal(t, []simpleVanishedInfo{{Func: "Handle", StartLine: 13, EndLine: 13}}, vanished)
+	})
 	t.Run("platform_dependent_code.go", func(t *testing.T) {
 		vanished := analyze(t, loadExample(t))
 		require.Empty(t, vanished)
@@ -213,4 +429,52 @@ func TestAnalysis(t *testing.T) {
 		vanished := analyze(t, loadExample(t))
 		require.Empty(t, vanished)
 	})
+	t.Run("line_directive.go", func(t *testing.T) {
+		vanished := analyze(t, loadExample(t))
+		require.Equal(t, []simpleVanishedInfo{{Func: "NoErrCheck", StartLine: 13, EndLine: 13}}, vanished)
+	})
+	t.Run("generated_code.go", func(t *testing.T) {
+		vanished := analyze(t, loadExample(t))
+		require.Empty(t, vanished)
+		vanished = analyzeWithFiles(t, loadExample(t), nil, GeneratedFiles{All: true})
+		require.Equal(t, []simpleVanishedInfo{{Func: "NoErrCheck", StartLine: 12, EndLine: 12, Generated: true}}, vanished)
+		vanished = analyzeWithFiles(t, loadExample(t), nil, GeneratedFiles{Globs: []string{"*.pb.go"}})
+		require.Empty(t, vanished)
+		vanished = analyzeWithFiles(t, loadExample(t), nil, GeneratedFiles{Globs: []string{"main.go"}})
+		require.Len(t, vanished, 1)
+	})
+	t.Run("cgo_usage.go", func(t *testing.T) {
+		vanished := analyzeWithFiles(t, loadExample(t), map[string]string{"add.c": "int add(int a, int b) { return a + b; }\n"}, GeneratedFiles{})
+		require.Equal(t, []simpleVanishedInfo{{Func: "NoErrCheck", StartLine: 22, EndLine: 22}}, vanished)
+	})
+}
+
+func TestAnalyzePackageFuncs(t *testing.T) {
+	data, err := os.ReadFile(path.Join("examples", "never_compiled_func.go"))
+	require.Nil(t, err)
+	dir, dispose, err := MustGenMod(strings.TrimPrefix(string(data), excludeComment))
+	require.Nil(t, err)
+	defer dispose()
+
+	assembly, err := AnalyzeModuleAssemblyParallel(dir, BuildConfig{}, 1, nil, nil)
+	require.Nil(t, err)
+	project, err := LoadPackage(dir)
+	require.Nil(t, err)
+	policy := &testPolicy{}
+	for _, pkg := range project {
+		AnalyzePackageFuncs(dir, pkg, assembly, GeneratedFiles{}, policy)
+	}
+	require.Equal(t, []simpleVanishedInfo{
+		{Func: "Push", StartLine: 6, EndLine: 6, FuncReason: FuncNeverCompiled},
+		{Func: "Generic", StartLine: 8, EndLine: 8, FuncReason: FuncNeverCompiled},
+	}, policy.Vanished)
+}
+
+func TestGeneratedFiles(t *testing.T) {
+	generated := GeneratedFiles{Globs: []string{"*.pb.go", "internal/mocks/*.go"}}
+	require.True(t, generated.Includes("/module", "/module/api/service.pb.go"))
+	require.True(t, generated.Includes("/module", "/module/internal/mocks/store.go"))
+	require.False(t, generated.Includes("/module", "/module/internal/mocks/nested/store.go"))
+	require.False(t, generated.Includes("/module", "/module/api/service_grpc.go"))
+	require.True(t, GeneratedFiles{All: true}.Includes("/module", "/module/api/service_grpc.go"))
 }
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AssemblyLineKind int
+
+const (
+	AssemblyUnknown AssemblyLineKind = iota
+	AssemblyEmpty
+	AssemblyPackageHeader // # example.com/module/pkg
+	AssemblySymbolHeader  // main.api STEXT size=113 args=0x10 locals=0x18 funcid=0x0 align=0x0
+	AssemblyInstruction   // \t0x0000 00000 (/module/main.go:6)\tTEXT\tmain.api(SB), ABIInternal, $24-8
+	AssemblyData          // \t0x0000 48 89 6c 24 10 48 8d 6c 24 10  H.l$.H.l$.
+	AssemblyRelocation    // \trel 3+4 t=R_CALL runtime.morestack_noctxt+0
+)
+
+type AssemblyToken struct {
+	Kind       AssemblyLineKind
+	Symbol     string // name of the symbol for AssemblySymbolHeader
+	SymbolKind string // kind of the symbol (STEXT, SRODATA, ...) for AssemblySymbolHeader
+	Opcode     string // opcode of AssemblyInstruction (MOVQ, CALL, TEXT, PCDATA, ...)
+	File       string // position of AssemblyInstruction, empty for pseudo positions like <autogenerated>
+	Line       int
+	// position in the compiled file if it differs from File:Line because of //line directive (cgo, code generators)
+	UnadjustedFile string
+	UnadjustedLine int
+}
+
+// assemblyPseudoOps generate no machine code of the line (NOP is either empty or alignment padding),
+// so they are not counted as instructions, but they still prove that the line was compiled
+var assemblyPseudoOps = NewSet("TEXT", "FUNCDATA", "PCDATA", "PCALIGN", "NOP")
+
+var symbolHeaderRegexp = regexp.MustCompile(`^(.*) (S[A-Z]+)((?: [a-z]+)*) size=\d+`)
+
+// TokenizeAssemblyLine recognizes single line of the compiler -S output
+// error is returned only for lines which look like instructions but have malformed position column
+func TokenizeAssemblyLine(line string) (AssemblyToken, error) {
+	if strings.TrimSpace(line) == "" {
+		return AssemblyToken{Kind: AssemblyEmpty}, nil
+	}
+	if strings.HasPrefix(line, "# ") {
+		return AssemblyToken{Kind: AssemblyPackageHeader}, nil
+	}
+	if !strings.HasPrefix(line, "\t") {
+		if match := symbolHeaderRegexp.FindStringSubmatch(line); match != nil {
+			return AssemblyToken{Kind: AssemblySymbolHeader, Symbol: match[1], SymbolKind: match[2]}, nil
+		}
+		return AssemblyToken{Kind: AssemblyUnknown}, nil
+	}
+	rest := line[1:]
+	if strings.HasPrefix(rest, "rel ") {
+		return AssemblyToken{Kind: AssemblyRelocation}, nil
+	}
+	offset, rest, _ := strings.Cut(rest, " ")
+	if !strings.HasPrefix(offset, "0x") || !isDigits(offset[2:], 16) {
+		return AssemblyToken{Kind: AssemblyUnknown}, nil
+	}
+	pc, rest, _ := strings.Cut(rest, " ")
+	// data lines consist of the hex bytes and their ascii dump: "0x0000 48 89 6c 24 ...  H.l$"
+	if len(pc) == 2 && isDigits(pc, 16) {
+		return AssemblyToken{Kind: AssemblyData}, nil
+	}
+	// instruction lines have zero-padded decimal pc followed by the position column
+	if len(pc) < 5 || !isDigits(pc, 10) || (!strings.HasPrefix(rest, "(") && !strings.HasPrefix(rest, "[")) {
+		return AssemblyToken{Kind: AssemblyUnknown}, nil
+	}
+	// position column is separated from the instruction by tab and file path can contain any other symbols
+	position, instruction, _ := strings.Cut(rest, "\t")
+	opcode, _, _ := strings.Cut(instruction, "\t")
+	closing := byte(')')
+	if position[0] == '[' {
+		closing = ']'
+	}
+	if len(position) < 2 || position[len(position)-1] != closing {
+		return AssemblyToken{}, fmt.Errorf("unterminated position column: %q", line)
+	}
+	position = position[1 : len(position)-1]
+	if strings.HasPrefix(position, "<") {
+		// <autogenerated>:1 and <unknown line number> positions doesn't refer to the source code
+		return AssemblyToken{Kind: AssemblyInstruction, Opcode: opcode}, nil
+	}
+	token := AssemblyToken{Kind: AssemblyInstruction, Opcode: opcode}
+	// positions affected by //line directive are printed as "adjusted.go:10[compiled.go:4]"
+	if separator := strings.LastIndex(position, "["); separator > 0 && strings.HasSuffix(position, "]") {
+		file, lineNumber, err := parseFileLine(position[separator+1 : len(position)-1])
+		if err != nil {
+			return AssemblyToken{}, fmt.Errorf("%w: %q", err, line)
+		}
+		token.UnadjustedFile, token.UnadjustedLine = file, lineNumber
+		position = position[:separator]
+	}
+	file, lineNumber, err := parseFileLine(position)
+	if err != nil {
+		return AssemblyToken{}, fmt.Errorf("%w: %q", err, line)
+	}
+	token.File, token.Line = file, lineNumber
+	return token, nil
+}
+
+func parseFileLine(position string) (string, int, error) {
+	separator := strings.LastIndex(position, ":")
+	if separator <= 0 {
+		return "", 0, fmt.Errorf("position without line number")
+	}
+	lineNumber, err := strconv.Atoi(position[separator+1:])
+	if err != nil || lineNumber <= 0 || !isDigits(position[separator+1:], 10) {
+		return "", 0, fmt.Errorf("invalid line number in position")
+	}
+	return position[:separator], lineNumber, nil
+}
+
+func isDigits(s string, base int) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		isDecimal := c >= '0' && c <= '9'
+		isHex := (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+		if !isDecimal && !(base == 16 && isHex) {
+			return false
+		}
+	}
+	return true
+}
+
+const maxAssemblyWarnings = 10
+
+// ModuleRoot maps import-path-relative positions (compiler produces them with -trimpath flag) back to the files on disk
+type ModuleRoot struct {
+	Path     string // module path from go.mod
+	Dir      string // module root directory
+	Replaced bool   `json:"-"` // dependency replaced with local directory which is analyzed together with the main module
+}
+
+// AssemblyParser collects source lines referenced by instructions of the compiler -S output
+type AssemblyParser struct {
+	Path          string // only files inside the Path directory are collected
+	Modules       []ModuleRoot
+	AssemblyLines AssemblyLines
+	Functions     AssemblyLines // entry positions of the function symbols (STEXT)
+	Instructions  InstructionCounts
+	warnings      int
+	inFunction    bool // next instruction is the entry of the function symbol
+}
+
+func NewAssemblyParser(path string, modules ...ModuleRoot) *AssemblyParser {
+	// prefer the longest module path in order to properly resolve files of the nested modules
+	modules = slices.Clone(modules)
+	sort.Slice(modules, func(i, j int) bool { return len(modules[i].Path) > len(modules[j].Path) })
+	return &AssemblyParser{Path: path, Modules: modules, AssemblyLines: make(AssemblyLines), Functions: make(AssemblyLines), Instructions: make(InstructionCounts)}
+}
+
+// ResolveFile converts file position from the compiler output to the absolute path of the file on disk
+func (p *AssemblyParser) ResolveFile(file string) (string, bool) {
+	if filepath.IsAbs(file) || strings.HasPrefix(file, "/") {
+		return file, true
+	}
+	for _, module := range p.Modules {
+		if relative, ok := strings.CutPrefix(file, module.Path+"/"); ok {
+			return filepath.Join(module.Dir, filepath.FromSlash(relative)), true
+		}
+		// dependencies are printed with version: example.com/module@v1.0.0/file.go
+		if versioned, ok := strings.CutPrefix(file, module.Path+"@"); ok {
+			if _, relative, ok := strings.Cut(versioned, "/"); ok {
+				return filepath.Join(module.Dir, filepath.FromSlash(relative)), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p *AssemblyParser) isAnalyzed(file string) bool {
+	if IsInsideDir(p.Path, file) {
+		return true
+	}
+	for _, module := range p.Modules {
+		if module.Replaced && IsInsideDir(module.Dir, file) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *AssemblyParser) warn(format string, args ...any) {
+	p.warnings++
+	if p.warnings <= maxAssemblyWarnings {
+		log.Printf("assembly parser warning: "+format, args...)
+	}
+}
+
+func (p *AssemblyParser) ParseLine(line string) {
+	token, err := TokenizeAssemblyLine(line)
+	if err != nil {
+		p.warn("%v", err)
+		return
+	}
+	switch token.Kind {
+	case AssemblyUnknown:
+		p.warn("unexpected line: %q", line)
+	case AssemblySymbolHeader:
+		p.inFunction = token.SymbolKind == "STEXT"
+	case AssemblyInstruction:
+		inFunction := p.inFunction
+		p.inFunction = false
+		file, lineNumber, ok := p.resolvePosition(token)
+		if !ok {
+			return
+		}
+		p.AssemblyLines[file] = append(p.AssemblyLines[file], lineNumber)
+		if !assemblyPseudoOps.Has(token.Opcode) {
+			p.Instructions.Add(file, lineNumber, 1)
+		}
+		if inFunction {
+			p.Functions[file] = append(p.Functions[file], lineNumber)
+		}
+	}
+}
+
+func (p *AssemblyParser) resolvePosition(token AssemblyToken) (string, int, bool) {
+	if token.File == "" {
+		return "", 0, false
+	}
+	// prefer position in the compiled file because many lines of the generated file can refer to the same template line
+	// cgo compiles temporary copies of the files, so only adjusted position points to the user file in this case
+	if file, ok := p.ResolveFile(token.UnadjustedFile); ok && token.UnadjustedFile != "" && p.isAnalyzed(file) {
+		return file, token.UnadjustedLine, true
+	} else if file, ok := p.ResolveFile(token.File); ok && p.isAnalyzed(file) {
+		return file, token.Line, true
+	}
+	return "", 0, false
+}
+
+func (p *AssemblyParser) Finish() Assembly {
+	if p.warnings > maxAssemblyWarnings {
+		log.Printf("assembly parser warning: %v more warnings suppressed", p.warnings-maxAssemblyWarnings)
+	}
+	assembly := Assembly{Lines: p.AssemblyLines, Functions: p.Functions, Instructions: p.Instructions}
+	assembly.Normalize()
+	return assembly
+}
+
+// IsInsideDir checks that file is located inside dir (but not in the sibling dir with the same prefix like /module-v2)
+func IsInsideDir(dir, file string) bool {
+	dir, file = filepath.ToSlash(filepath.Clean(dir)), filepath.ToSlash(file)
+	return strings.HasPrefix(file, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func ParseAssemblyOutput(path string, scanner *bufio.Scanner, modules ...ModuleRoot) AssemblyLines {
+	return ParseAssembly(path, scanner, modules...).Lines
+}
+
+func ParseAssembly(path string, scanner *bufio.Scanner, modules ...ModuleRoot) Assembly {
+	parser := NewAssemblyParser(path, modules...)
+	for scanner.Scan() {
+		parser.ParseLine(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		parser.warn("unable to read assembly output: %v", err)
+	}
+	return parser.Finish()
+}
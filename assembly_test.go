@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizeAssemblyLine(t *testing.T) {
+	t.Run("instruction", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("\t0x0000 00000 (/module/main.go:6)\tTEXT\tmain.api(SB), ABIInternal, $24-8")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblyInstruction, Opcode: "TEXT", File: "/module/main.go", Line: 6}, token)
+	})
+	t.Run("instruction with square brackets", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("\t0x0004 00004 [/module/main.go:7]\tJLS\t82")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblyInstruction, Opcode: "JLS", File: "/module/main.go", Line: 7}, token)
+	})
+	t.Run("path with special symbols", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("\t0x0004 00004 (/my (copy):module/main.go:7)\tJLS\t82")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblyInstruction, Opcode: "JLS", File: "/my (copy):module/main.go", Line: 7}, token)
+	})
+	t.Run("line directive positions", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("\t0x0012 00018 (/module/main.go:7[main.cgo1.go:10])\tCALL\tmain._Cfunc_add(SB)")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{
+			Kind:           AssemblyInstruction,
+			Opcode:         "CALL",
+			File:           "/module/main.go",
+			Line:           7,
+			UnadjustedFile: "main.cgo1.go",
+			UnadjustedLine: 10,
+		}, token)
+		_, err = TokenizeAssemblyLine("\t0x0012 00018 (/module/main.go:7[main.cgo1.go:x])\tCALL\tmain._Cfunc_add(SB)")
+		require.NotNil(t, err)
+	})
+	t.Run("pseudo positions", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("\t0x0000 00000 (<autogenerated>:1)\tTEXT\tmain.(*E).Error(SB), DUPOK|WRAPPER|ABIInternal, $40-16")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblyInstruction, Opcode: "TEXT"}, token)
+		token, err = TokenizeAssemblyLine("\t0x0020 00032 (<unknown line number>)\tNOP")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblyInstruction, Opcode: "NOP"}, token)
+	})
+	t.Run("symbol header", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("main.(*E).Error STEXT dupok size=97 args=0x10 locals=0x28 funcid=0x16 align=0x0")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblySymbolHeader, Symbol: "main.(*E).Error", SymbolKind: "STEXT"}, token)
+		token, err = TokenizeAssemblyLine(" SDWARFVAR size=41 align=0x0")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblySymbolHeader, Symbol: "", SymbolKind: "SDWARFVAR"}, token)
+		token, err = TokenizeAssemblyLine("type:struct { Name int32; Typ int32 } SRODATA dupok size=128 align=0x8")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyToken{Kind: AssemblySymbolHeader, Symbol: "type:struct { Name int32; Typ int32 }", SymbolKind: "SRODATA"}, token)
+	})
+	t.Run("data and relocations", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("\t0x0000 2f 6d 6f 64 75 6c 65 2f 6d 61 69 6e 2e 67 6f 3a  /module/main.go:")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyData, token.Kind)
+		token, err = TokenizeAssemblyLine("\t0x0000 28                                               (")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyData, token.Kind)
+		token, err = TokenizeAssemblyLine("\trel 3+4 t=R_CALL runtime.morestack_noctxt+0")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyRelocation, token.Kind)
+	})
+	t.Run("malformed positions", func(t *testing.T) {
+		for _, line := range []string{
+			"\t0x0000 00000 (/module/main.go\tTEXT",
+			"\t0x0000 00000 (/module/main.go)\tTEXT",
+			"\t0x0000 00000 (/module/main.go:x)\tTEXT",
+			"\t0x0000 00000 (/module/main.go:-1)\tTEXT",
+			"\t0x0000 00000 (",
+		} {
+			_, err := TokenizeAssemblyLine(line)
+			require.NotNil(t, err, line)
+		}
+	})
+	t.Run("unknown line", func(t *testing.T) {
+		token, err := TokenizeAssemblyLine("./main.go:5:2: undefined: x")
+		require.Nil(t, err)
+		require.Equal(t, AssemblyUnknown, token.Kind)
+	})
+}
+
+func TestParseAssemblyOutput(t *testing.T) {
+	output := strings.Join([]string{
+		"# example.com/module",
+		"main.api STEXT size=113 args=0x10 locals=0x18 funcid=0x0 align=0x0",
+		"\t0x0000 00000 (/module/main.go:6)\tTEXT\tmain.api(SB), ABIInternal, $24-8",
+		"\t0x0004 00004 (/module/main.go:8)\tJLS\t82",
+		"\t0x0004 00004 (/module/main.go:7)\tJLS\t82",
+		"\t0x0006 00006 (/module/main.go:7)\tPUSHQ\tBP",
+		"\t0x0000 00000 (/module-v2/main.go:1)\tTEXT\tmain.api(SB), ABIInternal, $24-8",
+		"\t0x0000 2f 6d 6f 64 75 6c 65 2f 6d 61 69 6e 2e 67 6f 3a  /module/main.go:",
+		"\t0x0000 00000 (/module/main.go)\tTEXT\tmain.api(SB), ABIInternal, $24-8",
+		"unexpected line /module/main.go",
+	}, "\n")
+	assemblyLines := ParseAssemblyOutput("/module", bufio.NewScanner(strings.NewReader(output)))
+	require.Equal(t, AssemblyLines{"/module/main.go": {6, 7, 8}}, assemblyLines)
+}
+
+func TestParseAssemblyOutputTrimpath(t *testing.T) {
+	output := strings.Join([]string{
+		"\t0x0000 00000 (example.com/module/main.go:6)\tTEXT\tmain.api(SB), ABIInternal, $24-8",
+		"\t0x0000 00000 (example.com/module/nested/pkg/lib.go:3)\tTEXT\texample.com/module/nested/pkg.Add(SB), ABIInternal, $0-16",
+		"\t0x0000 00000 (example.com/module-v2/main.go:1)\tTEXT\tmain.api(SB), ABIInternal, $24-8",
+		"\t0x0000 00000 (fmt/print.go:10)\tTEXT\tfmt.Println(SB), ABIInternal, $24-8",
+	}, "\n")
+	modules := []ModuleRoot{
+		{Path: "example.com/module", Dir: "/module"},
+		{Path: "example.com/module/nested", Dir: "/nested"},
+	}
+	assemblyLines := ParseAssemblyOutput("/", bufio.NewScanner(strings.NewReader(output)), modules...)
+	require.Equal(t, AssemblyLines{"/module/main.go": {6}, "/nested/pkg/lib.go": {3}}, assemblyLines)
+}
+
+func TestParseAssemblyOutputReplaced(t *testing.T) {
+	output := strings.Join([]string{
+		"\t0x0000 00000 (/module/main.go:6)\tTEXT\tmain.main(SB), ABIInternal, $24-8",
+		"\t0x0000 00000 (/shared/shared.go:3)\tTEXT\texample.com/shared.Check(SB), ABIInternal, $24-16",
+		"\t0x0000 00000 (example.com/shared@v0.0.0/shared.go:4)\tTEXT\texample.com/shared.Check(SB), ABIInternal, $24-16",
+		"\t0x0000 00000 (/other/other.go:3)\tTEXT\texample.com/other.Run(SB), ABIInternal, $24-16",
+	}, "\n")
+	modules := []ModuleRoot{
+		{Path: "example.com/module", Dir: "/module"},
+		{Path: "example.com/shared", Dir: "/shared", Replaced: true},
+		{Path: "example.com/other", Dir: "/other"},
+	}
+	assemblyLines := ParseAssemblyOutput("/module", bufio.NewScanner(strings.NewReader(output)), modules...)
+	require.Equal(t, AssemblyLines{"/module/main.go": {6}, "/shared/shared.go": {3, 4}}, assemblyLines)
+}
+
+func TestParseAssemblyOutputLineDirectives(t *testing.T) {
+	output := strings.Join([]string{
+		// generated file: lines of the compiled file are collected
+		"\t0x0000 00000 (/module/parser.rl:3[/module/parser.go:4])\tTEXT\tmain.Parse(SB), ABIInternal, $24-16",
+		"\t0x000e 00014 (example.com/module/parser.rl:5[example.com/module/parser.go:9])\tMOVQ\tBX, main.w+40(SP)",
+		// cgo: compiled file is temporary copy, so adjusted position is used
+		"\t0x0012 00018 (/module/main.go:7[main.cgo1.go:10])\tCALL\tmain._Cfunc_add(SB)",
+	}, "\n")
+	modules := []ModuleRoot{{Path: "example.com/module", Dir: "/module"}}
+	assemblyLines := ParseAssemblyOutput("/module", bufio.NewScanner(strings.NewReader(output)), modules...)
+	require.Equal(t, AssemblyLines{"/module/parser.go": {4, 9}, "/module/main.go": {7}}, assemblyLines)
+}
+
+func TestParseAssemblyFunctions(t *testing.T) {
+	output := strings.Join([]string{
+		"main.api STEXT size=113 args=0x10 locals=0x18 funcid=0x0 align=0x0",
+		"\t0x0000 00000 (/module/main.go:6)\tTEXT\tmain.api(SB), ABIInternal, $24-8",
+		"\t0x0004 00004 (/module/main.go:7)\tJLS\t82",
+		"main.(*E).Error STEXT dupok size=97 args=0x10 locals=0x28 funcid=0x16 align=0x0",
+		"\t0x0000 00000 (<autogenerated>:1)\tTEXT\tmain.(*E).Error(SB), DUPOK|WRAPPER|ABIInternal, $40-16",
+		"\t0x0004 00004 (/module/main.go:3)\tJLS\t82",
+		"main..stmp_0 SRODATA static size=16",
+		"\t0x0000 00000 (/module/main.go:12)\tTEXT\tmain.main(SB), ABIInternal, $24-8",
+	}, "\n")
+	assembly := ParseAssembly("/module", bufio.NewScanner(strings.NewReader(output)))
+	require.Equal(t, AssemblyLines{"/module/main.go": {3, 6, 7, 12}}, assembly.Lines)
+	require.Equal(t, AssemblyLines{"/module/main.go": {6}}, assembly.Functions)
+	require.Equal(t, InstructionCounts{"/module/main.go": {3: 1, 7: 1}}, assembly.Instructions)
+}
+
+func TestAssemblyInstructionCounts(t *testing.T) {
+	dir, dispose, err := MustGenMod(`package main
+
+//go:noinline
+func Add(a, b int) int {
+	return a + b
+}
+
+func main() { println(Add(1, 2)) }`)
+	require.Nil(t, err)
+	defer dispose()
+
+	assembly, err := AnalyzeModuleAssemblyParallel(dir, BuildConfig{}, 1, nil, nil)
+	require.Nil(t, err)
+	mainPath := filepath.Join(dir, "main.go")
+	// signature line has only TEXT, FUNCDATA and PCDATA pseudo instructions of the leaf function
+	require.Contains(t, assembly.Lines[mainPath], 4)
+	require.Zero(t, assembly.Instructions[mainPath][4])
+	require.Positive(t, assembly.Instructions[mainPath][5])
+}
+
+func FuzzTokenizeAssemblyLine(f *testing.F) {
+	f.Add("\t0x0000 00000 (/module/main.go:6)\tTEXT\tmain.api(SB), ABIInternal, $24-8")
+	f.Add("\t0x0004 00004 [/module/main.go:7]\tJLS\t82")
+	f.Add("\t0x0000 00000 (<autogenerated>:1)\tTEXT")
+	f.Add("\t0x0000 48 89 6c 24 10 48 8d 6c 24 10  H.l$.H.l$.")
+	f.Add("\trel 3+4 t=R_CALL runtime.morestack_noctxt+0")
+	f.Add("main.api STEXT size=113 args=0x10 locals=0x18 funcid=0x0 align=0x0")
+	f.Add("# example.com/module")
+	f.Fuzz(func(t *testing.T, line string) {
+		token, err := TokenizeAssemblyLine(line)
+		if err != nil || token.Kind != AssemblyInstruction || token.File == "" {
+			return
+		}
+		require.Positive(t, token.Line)
+		require.True(t, strings.Contains(line, token.File))
+		_ = ParseAssemblyOutput("/module", bufio.NewScanner(strings.NewReader(line)))
+	})
+}
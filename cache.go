@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const assemblyCacheVersion = "govanish-assembly-cache-v6"
+
+// AssemblyCache stores parsed assembly of the packages on disk, so unchanged packages are not compiled again
+// nil *AssemblyCache is valid and behaves like always empty cache
+type AssemblyCache struct {
+	dir   string
+	salt  string
+	build BuildConfig
+}
+
+func OpenAssemblyCache(config BuildConfig) (*AssemblyCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(cacheDir, "govanish")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// toolchain, target platform, C toolchain of cgo packages and flags affects the compiler output
+	cmd := config.Command(
+		"env", "GOVERSION", "GOOS", "GOARCH", "GOFLAGS", "GOEXPERIMENT", "GOAMD64", "GOARM64",
+		"CGO_ENABLED", "CC", "CXX", "CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_LDFLAGS",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(`go env failed: err=%w, cmd="%v"`, err, cmd)
+	}
+	profileHash := sha256.New()
+	if mode := config.PgoMode(); mode != "auto" && mode != "off" {
+		writeFileHash(profileHash, mode)
+	}
+	// -S flag is enabled for the analyzed replaced modules too, so they change the assembly printed for every package
+	replaced := make([]string, 0, len(config.Replaced))
+	for _, module := range config.Replaced {
+		replaced = append(replaced, module.Path+"="+module.Dir)
+	}
+	salt := strings.Join([]string{
+		hex.EncodeToString(profileHash.Sum(nil)),
+		assemblyCacheVersion,
+		string(output),
+		strings.Join(config.Env, "\n"),
+		strings.Join(config.Flags, "\n"),
+		strings.Join(config.Gcflags, "\n"),
+		strings.Join(replaced, "\n"),
+	}, "\n")
+	return &AssemblyCache{dir: dir, salt: salt, build: config}, nil
+}
+
+// PackageKeys computes cache key for every package based on the content of its files and files of its dependencies from the module
+// dependencies outside of the module are accounted through go.mod and go.sum content and sources of the local replacements
+func (c *AssemblyCache) PackageKeys(path string, pkgs []ModulePackage) map[string]string {
+	if c == nil {
+		return nil
+	}
+	replaced, err := ListLocalReplacements(path, c.build)
+	if err != nil {
+		log.Printf("packages will not be cached: %v", err)
+		return nil
+	}
+	moduleHash := sha256.New()
+	_, _ = io.WriteString(moduleHash, c.salt)
+	_, _ = io.WriteString(moduleHash, path)
+	for _, name := range []string{"go.mod", "go.sum", "go.work", "go.work.sum", "vendor/modules.txt"} {
+		writeFileHash(moduleHash, filepath.Join(path, name))
+	}
+	// go.sum doesn't cover local replacements, so content of their files is hashed
+	for _, module := range replaced {
+		writeDirHash(moduleHash, module.Dir)
+	}
+	// profiles of the main packages affects compilation of all their dependencies
+	for _, profile := range DetectPgoProfiles(pkgs) {
+		writeFileHash(moduleHash, profile)
+	}
+	moduleSum := moduleHash.Sum(nil)
+
+	pkgSums := make(map[string][]byte, len(pkgs))
+	for _, pkg := range pkgs {
+		pkgHash := sha256.New()
+		_, _ = io.WriteString(pkgHash, pkg.ImportPath)
+		for _, files := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.HFiles, pkg.SFiles, pkg.EmbedFiles} {
+			for _, file := range files {
+				writeFileHash(pkgHash, filepath.Join(pkg.Dir, file))
+			}
+		}
+		pkgSums[pkg.ImportPath] = pkgHash.Sum(nil)
+	}
+
+	keys := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		keyHash := sha256.New()
+		keyHash.Write(moduleSum)
+		keyHash.Write(pkgSums[pkg.ImportPath])
+		deps := append([]string(nil), pkg.Deps...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if sum, ok := pkgSums[dep]; ok {
+				keyHash.Write(sum)
+			}
+		}
+		keys[pkg.ImportPath] = hex.EncodeToString(keyHash.Sum(nil))
+	}
+	return keys
+}
+
+func writeFileHash(h hash.Hash, path string) {
+	_, _ = io.WriteString(h, path)
+	f, err := os.Open(path)
+	if err != nil {
+		_, _ = io.WriteString(h, "<missing>")
+		return
+	}
+	defer f.Close()
+	_, _ = io.Copy(h, f)
+}
+
+func writeDirHash(h hash.Hash, dir string) {
+	snapshot, err := TakeModuleSnapshot(dir)
+	if err != nil {
+		_, _ = io.WriteString(h, dir+"<missing>")
+		return
+	}
+	for _, path := range slices.Sorted(maps.Keys(snapshot)) {
+		writeFileHash(h, path)
+	}
+}
+
+func (c *AssemblyCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+func (c *AssemblyCache) Load(key string) (Assembly, bool) {
+	if c == nil || key == "" {
+		return Assembly{}, false
+	}
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return Assembly{}, false
+	}
+	assembly := NewAssembly()
+	if err := json.Unmarshal(data, &assembly); err != nil {
+		log.Printf("ignoring corrupted cache entry %v: %v", key, err)
+		return Assembly{}, false
+	}
+	return assembly, true
+}
+
+func (c *AssemblyCache) Store(key string, assembly Assembly) {
+	if c == nil || key == "" {
+		return
+	}
+	data, err := json.Marshal(assembly)
+	if err != nil {
+		log.Printf("unable to encode cache entry %v: %v", key, err)
+		return
+	}
+	entryPath := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0o755); err != nil {
+		log.Printf("unable to create cache directory: %v", err)
+		return
+	}
+	// write through temporary file in order to never expose partially written entry to the concurrent runs
+	f, err := os.CreateTemp(filepath.Dir(entryPath), key+".*.tmp")
+	if err != nil {
+		log.Printf("unable to create cache entry %v: %v", key, err)
+		return
+	}
+	_, writeErr := f.Write(data)
+	closeErr := f.Close()
+	if writeErr != nil || closeErr != nil {
+		_ = os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), entryPath); err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssemblyCache(t *testing.T) {
+	t.Run("store and load", func(t *testing.T) {
+		cache := &AssemblyCache{dir: t.TempDir(), salt: "test"}
+		_, ok := cache.Load("abcdef")
+		require.False(t, ok)
+		assembly := Assembly{
+			Lines:        AssemblyLines{"/main.go": {1, 2, 3}},
+			Functions:    AssemblyLines{"/main.go": {1}},
+			Instructions: InstructionCounts{"/main.go": {1: 2, 2: 1, 3: 4}},
+		}
+		cache.Store("abcdef", assembly)
+		loaded, ok := cache.Load("abcdef")
+		require.True(t, ok)
+		require.Equal(t, assembly, loaded)
+	})
+	t.Run("nil cache", func(t *testing.T) {
+		var cache *AssemblyCache
+		cache.Store("abcdef", Assembly{Lines: AssemblyLines{"/main.go": {1}}})
+		_, ok := cache.Load("abcdef")
+		require.False(t, ok)
+		require.Nil(t, cache.PackageKeys("/", []ModulePackage{{ImportPath: "a"}}))
+	})
+	t.Run("keys depend on dependencies content", func(t *testing.T) {
+		dir := t.TempDir()
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module m\n"), 0o644))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0o644))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b"), 0o644))
+		cache := &AssemblyCache{dir: t.TempDir(), salt: "test"}
+		pkgs := []ModulePackage{
+			{ImportPath: "m/a", Dir: dir, GoFiles: []string{"a.go"}, Deps: []string{"fmt", "m/b"}},
+			{ImportPath: "m/b", Dir: dir, GoFiles: []string{"b.go"}, Deps: []string{"fmt"}},
+		}
+		before := cache.PackageKeys(dir, pkgs)
+		require.Equal(t, before, cache.PackageKeys(dir, pkgs))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n"), 0o644))
+		after := cache.PackageKeys(dir, pkgs)
+		require.NotEqual(t, before["m/a"], after["m/a"])
+		require.NotEqual(t, before["m/b"], after["m/b"])
+	})
+	t.Run("keys depend on local replacements", func(t *testing.T) {
+		root := t.TempDir()
+		dir, shared := filepath.Join(root, "m"), filepath.Join(root, "shared")
+		require.Nil(t, os.MkdirAll(dir, 0o755))
+		require.Nil(t, os.MkdirAll(shared, 0o755))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module m\n\nreplace example.com/shared => ../shared\n"), 0o644))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0o644))
+		require.Nil(t, os.WriteFile(filepath.Join(shared, "shared.go"), []byte("package shared"), 0o644))
+		cache := &AssemblyCache{dir: t.TempDir(), salt: "test"}
+		pkgs := []ModulePackage{{ImportPath: "m/a", Dir: dir, GoFiles: []string{"a.go"}, Deps: []string{"example.com/shared"}}}
+		before := cache.PackageKeys(dir, pkgs)
+		require.Len(t, before, 1)
+		require.Nil(t, os.WriteFile(filepath.Join(shared, "shared.go"), []byte("package shared\n"), 0o644))
+		require.NotEqual(t, before, cache.PackageKeys(dir, pkgs))
+	})
+	t.Run("salt depends on replaced modules", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		plain, err := OpenAssemblyCache(BuildConfig{})
+		require.Nil(t, err)
+		replaced, err := OpenAssemblyCache(BuildConfig{Replaced: []ModuleRoot{{Path: "example.com/shared", Dir: "/shared", Replaced: true}}})
+		require.Nil(t, err)
+		require.NotEqual(t, plain.salt, replaced.salt)
+	})
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+type CollectReporting struct{ Vanished []VanishedInfo }
+
+func (r *CollectReporting) ReportVanished(info VanishedInfo) { r.Vanished = append(r.Vanished, info) }
+
+// ResolveToolchain locates locally installed go toolchain by its GOROOT path or by its name (like go1.23)
+// we never let go command to download toolchain implicitly - comparison must be reproducible offline
+func ResolveToolchain(spec string) (BuildConfig, error) {
+	if stat, err := os.Stat(spec); err == nil && stat.IsDir() {
+		return goRootToolchain(spec)
+	}
+	if goBinary, err := exec.LookPath(spec); err == nil {
+		// wrappers from golang.org/dl/goX.Y are installed as separate binaries
+		return BuildConfig{GoBinary: goBinary, Env: []string{"GOTOOLCHAIN=local"}}, nil
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if stat, err := os.Stat(filepath.Join(home, "sdk", spec)); err == nil && stat.IsDir() {
+			return goRootToolchain(filepath.Join(home, "sdk", spec))
+		}
+	}
+	return BuildConfig{}, fmt.Errorf("toolchain '%v' not found: provide GOROOT path or install it locally (golang.org/dl)", spec)
+}
+
+func goRootToolchain(goRoot string) (BuildConfig, error) {
+	goRoot, err := filepath.Abs(goRoot)
+	if err != nil {
+		return BuildConfig{}, err
+	}
+	goBinary := filepath.Join(goRoot, "bin", "go")
+	if _, err := os.Stat(goBinary); err != nil {
+		return BuildConfig{}, fmt.Errorf("directory '%v' is not a GOROOT: %w", goRoot, err)
+	}
+	return BuildConfig{GoBinary: goBinary, Env: []string{"GOROOT=" + goRoot, "GOTOOLCHAIN=local"}}, nil
+}
+
+func ToolchainVersion(config BuildConfig) string {
+	output, err := config.Command("env", "GOVERSION").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func VanishedKey(info VanishedInfo) string {
+	relativePath, _ := filepath.Rel(info.AnalysisPath, info.Filename())
+	return fmt.Sprintf("%v:%v-%v:%v", relativePath, info.StartLine(), info.EndLine(), info.FuncName)
+}
+
+// DiffVanished splits findings into newly vanished (present only in head), reappeared (present only in base) and persisting ones
+// findings with the same key are matched by the number of occurrences, so new duplicate of the persisting finding is reported as added
+func DiffVanished(base, head []VanishedInfo, key func(VanishedInfo) string) (added, removed, persisting []VanishedInfo) {
+	baseCounts, headCounts := make(map[string]int), make(map[string]int)
+	for _, info := range base {
+		baseCounts[key(info)]++
+	}
+	for _, info := range head {
+		headCounts[key(info)]++
+	}
+	for _, info := range head {
+		if baseCounts[key(info)] > 0 {
+			baseCounts[key(info)]--
+			persisting = append(persisting, info)
+		} else {
+			added = append(added, info)
+		}
+	}
+	for _, info := range base {
+		if headCounts[key(info)] > 0 {
+			headCounts[key(info)]--
+		} else {
+			removed = append(removed, info)
+		}
+	}
+	return added, removed, persisting
+}
+
+func AnalyzeModuleVanished(analysisPath string, project []*packages.Package, funcRegistry FuncRegistry, config BuildConfig, generated GeneratedFiles) ([]VanishedInfo, error) {
+	assemblyLines, err := AnalyzeModuleAssemblyWithConfig(analysisPath, config)
+	if len(assemblyLines) == 0 && err != nil {
+		return nil, fmt.Errorf("failed to analyze module assembly: %w", err)
+	}
+	if err != nil {
+		log.Printf("module analysis finished with non-critical error: %v", err)
+	}
+	collect := &CollectReporting{}
+	err = AnalyzeModuleAst(analysisPath, project, assemblyLines, funcRegistry, generated, Govanish, collect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze module AST: %w", err)
+	}
+	return collect.Vanished, nil
+}
+
+func CompareToolchains(analysisPath string, build BuildConfig, generated GeneratedFiles, toolchains []string, reporting Reporting) error {
+	if len(toolchains) != 2 {
+		return fmt.Errorf("exactly two -toolchain values expected, got %v", len(toolchains))
+	}
+	project, err := LoadPackages(analysisPath, build.Patterns(), build.Flags...)
+	if err != nil {
+		return fmt.Errorf("unable to load project '%v': %w", analysisPath, err)
+	}
+	funcRegistry := CreateFuncRegistry(project)
+
+	results := make([][]VanishedInfo, 0, len(toolchains))
+	versions := make([]string, 0, len(toolchains))
+	for _, toolchain := range toolchains {
+		config, err := ResolveToolchain(toolchain)
+		if err != nil {
+			return err
+		}
+		config.Flags, config.Gcflags = build.Flags, build.Gcflags
+		version := ToolchainVersion(config)
+		log.Printf("analyzing module with toolchain %v (%v)", toolchain, version)
+		vanished, err := AnalyzeModuleVanished(analysisPath, project, funcRegistry, config, generated)
+		if err != nil {
+			return fmt.Errorf("toolchain %v: %w", toolchain, err)
+		}
+		results = append(results, vanished)
+		versions = append(versions, version)
+	}
+
+	added, removed, _ := DiffVanished(results[0], results[1], VanishedKey)
+	log.Printf("compared %v against %v: %v newly vanished, %v reappeared", versions[1], versions[0], len(added), len(removed))
+	for _, info := range removed {
+		log.Printf(
+			"code reappeared in compiled binary with %v: func=[%v], file=[%v], lines=[%v-%v]",
+			versions[1],
+			info.FuncName,
+			info.Filename(),
+			info.StartLine(),
+			info.EndLine(),
+		)
+	}
+	for _, info := range added {
+		reporting.ReportVanished(info)
+	}
+	return nil
+}
+
+// SnippetKey identifies finding independently of its position, so it survives unrelated edits which shift lines
+func SnippetKey(info VanishedInfo) string {
+	return fmt.Sprintf("%v.%v:%v", info.Pkg.PkgPath, info.QualifiedFuncName(), strings.Join(strings.Fields(info.Snippet()), " "))
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf(`git failed: err=%w, cmd="%v", output=%v`, err, cmd, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CheckoutWorktree checks out revision of the repository containing analysisPath into the temporary git worktree
+// and returns path of the module inside it together with cleanup function
+func CheckoutWorktree(analysisPath string, revision string) (string, func(), error) {
+	topLevel, err := gitOutput(analysisPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	topLevel, err = filepath.EvalSymlinks(topLevel)
+	if err != nil {
+		return "", nil, err
+	}
+	modulePath, err := filepath.EvalSymlinks(analysisPath)
+	if err != nil {
+		return "", nil, err
+	}
+	relativePath, err := filepath.Rel(topLevel, modulePath)
+	if err != nil {
+		return "", nil, err
+	}
+	commit, err := gitOutput(analysisPath, "rev-parse", "--verify", revision+"^{commit}")
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to resolve revision '%v': %w", revision, err)
+	}
+	dir, err := os.MkdirTemp("", "govanish-base-*")
+	if err != nil {
+		return "", nil, err
+	}
+	dispose := func() {
+		if _, err := gitOutput(topLevel, "worktree", "remove", "--force", dir); err != nil {
+			log.Printf("unable to remove worktree '%v': %v", dir, err)
+		}
+		_ = os.RemoveAll(dir)
+	}
+	if _, err := gitOutput(topLevel, "worktree", "add", "--detach", dir, commit); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, err
+	}
+	log.Printf("checked out revision %v (%v) into worktree '%v'", revision, commit, dir)
+	return filepath.Join(dir, relativePath), dispose, nil
+}
+
+func analyzeRevision(analysisPath string, build BuildConfig, generated GeneratedFiles) ([]VanishedInfo, error) {
+	project, err := LoadPackages(analysisPath, build.Patterns(), build.Flags...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load project '%v': %w", analysisPath, err)
+	}
+	funcRegistry := CreateFuncRegistry(project)
+	return AnalyzeModuleVanished(analysisPath, project, funcRegistry, build, generated)
+}
+
+func CompareRevisions(analysisPath string, build BuildConfig, generated GeneratedFiles, baseRevision string, reporting Reporting, summary io.Writer) error {
+	basePath, dispose, err := CheckoutWorktree(analysisPath, baseRevision)
+	if err != nil {
+		return err
+	}
+	defer dispose()
+
+	baseBuild := build
+	if len(build.Replaced) > 0 {
+		// relative replacements of the base revision must point inside its worktree
+		baseBuild.Replaced, err = ListLocalReplacements(basePath, build)
+		if err != nil {
+			return fmt.Errorf("base revision %v: %w", baseRevision, err)
+		}
+	}
+	base, err := analyzeRevision(basePath, baseBuild, generated)
+	if err != nil {
+		return fmt.Errorf("base revision %v: %w", baseRevision, err)
+	}
+	head, err := analyzeRevision(analysisPath, build, generated)
+	if err != nil {
+		return fmt.Errorf("working tree: %w", err)
+	}
+
+	added, fixed, persisting := DiffVanished(base, head, SnippetKey)
+	log.Printf("compared against %v: %v newly vanished, %v fixed, %v persisting", baseRevision, len(added), len(fixed), len(persisting))
+	for _, info := range added {
+		reporting.ReportVanished(info)
+	}
+	if summary != nil {
+		WriteCompareSummary(summary, baseRevision, added, fixed, persisting)
+	}
+	return nil
+}
+
+// WriteCompareSummary renders comparison as markdown suitable for the PR comment or GitHub job summary
+func WriteCompareSummary(w io.Writer, baseRevision string, added, fixed, persisting []VanishedInfo) {
+	_, _ = fmt.Fprintf(w, "### govanish: compared with `%v`\n\n", baseRevision)
+	_, _ = fmt.Fprintf(w, "| newly vanished | fixed | persisting |\n|---|---|---|\n| %v | %v | %v |\n", len(added), len(fixed), len(persisting))
+	sections := []struct {
+		title    string
+		vanished []VanishedInfo
+	}{
+		{title: "Newly vanished", vanished: added},
+		{title: "Fixed", vanished: fixed},
+		{title: "Persisting", vanished: persisting},
+	}
+	for _, section := range sections {
+		if len(section.vanished) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "\n#### %v\n\n", section.title)
+		for _, info := range section.vanished {
+			relativePath, _ := filepath.Rel(info.AnalysisPath, info.Filename())
+			_, _ = fmt.Fprintf(w, "- `%v` in `%v:%v`: `%v`\n", info.FuncName, relativePath, info.StartLine(), strings.Join(strings.Fields(info.Snippet()), " "))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffVanished(t *testing.T) {
+	funcName := func(info VanishedInfo) string { return info.FuncName }
+	t.Run("added and removed", func(t *testing.T) {
+		base := []VanishedInfo{{FuncName: "A"}, {FuncName: "B"}}
+		head := []VanishedInfo{{FuncName: "B"}, {FuncName: "C"}}
+		added, removed, persisting := DiffVanished(base, head, funcName)
+		require.Equal(t, []VanishedInfo{{FuncName: "C"}}, added)
+		require.Equal(t, []VanishedInfo{{FuncName: "A"}}, removed)
+		require.Equal(t, []VanishedInfo{{FuncName: "B"}}, persisting)
+	})
+	t.Run("same findings", func(t *testing.T) {
+		base := []VanishedInfo{{FuncName: "A"}}
+		added, removed, persisting := DiffVanished(base, base, funcName)
+		require.Empty(t, added)
+		require.Empty(t, removed)
+		require.Equal(t, base, persisting)
+	})
+	t.Run("duplicate keys", func(t *testing.T) {
+		base := []VanishedInfo{{FuncName: "A", AnalysisPath: "first"}, {FuncName: "B"}, {FuncName: "B"}}
+		head := []VanishedInfo{{FuncName: "A", AnalysisPath: "first"}, {FuncName: "A", AnalysisPath: "second"}, {FuncName: "B"}}
+		added, removed, persisting := DiffVanished(base, head, funcName)
+		require.Equal(t, []VanishedInfo{{FuncName: "A", AnalysisPath: "second"}}, added)
+		require.Equal(t, []VanishedInfo{{FuncName: "B"}}, removed)
+		require.Equal(t, []VanishedInfo{{FuncName: "A", AnalysisPath: "first"}, {FuncName: "B"}}, persisting)
+	})
+}
+
+func TestCheckoutWorktree(t *testing.T) {
+	repository, err := filepath.EvalSymlinks(t.TempDir())
+	require.Nil(t, err)
+	module := filepath.Join(repository, "module")
+	require.Nil(t, os.Mkdir(module, 0o755))
+	git := func(args ...string) string {
+		output, err := gitOutput(repository, append([]string{"-c", "user.name=govanish", "-c", "user.email=govanish@example.com"}, args...)...)
+		require.Nil(t, err)
+		return output
+	}
+	git("init", "-q")
+	require.Nil(t, os.WriteFile(filepath.Join(module, "main.go"), []byte("package main // base\n"), 0o644))
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	require.Nil(t, os.WriteFile(filepath.Join(module, "main.go"), []byte("package main // head\n"), 0o644))
+
+	t.Run("module of the base revision", func(t *testing.T) {
+		basePath, dispose, err := CheckoutWorktree(module, "HEAD")
+		require.Nil(t, err)
+		require.Equal(t, "module", filepath.Base(basePath))
+		content, err := os.ReadFile(filepath.Join(basePath, "main.go"))
+		require.Nil(t, err)
+		require.Equal(t, "package main // base\n", string(content))
+
+		dispose()
+		_, err = os.Stat(basePath)
+		require.True(t, os.IsNotExist(err))
+		require.NotContains(t, git("worktree", "list"), filepath.Dir(basePath))
+	})
+	t.Run("unknown revision", func(t *testing.T) {
+		_, _, err := CheckoutWorktree(module, "no-such-revision")
+		require.ErrorContains(t, err, "unable to resolve revision 'no-such-revision'")
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// guardedBlock is the body of the if statement together with statements preceding the if in the enclosing block
+type guardedBlock struct {
+	IfStmt    *ast.IfStmt
+	Preceding []ast.Stmt
+}
+
+var errorInterface = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func isErrorVar(obj types.Object) (*types.Var, bool) {
+	v, ok := obj.(*types.Var)
+	return v, ok && types.Implements(v.Type(), errorInterface)
+}
+
+// UncheckedError is the `if X != nil` condition which most likely was meant to guard the nearest preceding assignment
+// of the different error, while it checks X instead
+type UncheckedError struct {
+	Checked     *ast.Ident // X in the condition
+	CheckedVar  *types.Var
+	Cond        ast.Expr
+	Assign      ast.Stmt   // assignment or variable declaration
+	AssignedVar *types.Var // nil if error is assigned to the blank identifier
+}
+
+// FindUncheckedError recognizes
+//
+//	_ = w.Write(2) // or err2 := w.Write(2), or var err2 = w.Write(2)
+//	if err != nil {
+//
+// where fresh error is not used between the assignment and the condition
+// statements which don't assign errors (like n := 1) are skipped while looking for the nearest assignment
+func FindUncheckedError(pkg *packages.Package, ifStmt *ast.IfStmt, preceding []ast.Stmt) (UncheckedError, bool) {
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return UncheckedError{}, false
+	}
+	checked, ok := cond.X.(*ast.Ident)
+	other := cond.Y
+	if !ok {
+		checked, ok = cond.Y.(*ast.Ident)
+		other = cond.X
+	}
+	if !ok || !pkg.TypesInfo.Types[other].IsNil() {
+		return UncheckedError{}, false
+	}
+	checkedVar, ok := isErrorVar(pkg.TypesInfo.Uses[checked])
+	if !ok {
+		return UncheckedError{}, false
+	}
+	for i := len(preceding) - 1; i >= 0; i-- {
+		lhs, lhsTypes := assignedOperands(pkg, preceding[i])
+		assignsError := false
+		for j, operand := range lhs {
+			ident, ok := operand.(*ast.Ident)
+			if !ok || lhsTypes[j] == nil || !types.Implements(lhsTypes[j], errorInterface) {
+				continue
+			}
+			assignsError = true
+			unchecked := UncheckedError{Checked: checked, CheckedVar: checkedVar, Cond: ifStmt.Cond, Assign: preceding[i]}
+			if ident.Name == "_" {
+				return unchecked, true
+			}
+			obj := pkg.TypesInfo.Defs[ident]
+			if obj == nil {
+				obj = pkg.TypesInfo.Uses[ident]
+			}
+			assignedVar, ok := obj.(*types.Var)
+			if !ok || assignedVar == checkedVar {
+				continue
+			}
+			used := []ast.Node{ifStmt.Init, ifStmt.Cond}
+			for _, stmt := range preceding[i+1:] {
+				used = append(used, stmt)
+			}
+			if usesVar(pkg, used, assignedVar) {
+				continue
+			}
+			unchecked.AssignedVar = assignedVar
+			return unchecked, true
+		}
+		// only the nearest assignment of the error is considered: condition is most likely written right after it
+		if assignsError {
+			return UncheckedError{}, false
+		}
+	}
+	return UncheckedError{}, false
+}
+
+// assignedOperands returns left hand side operands of the assignment (or names of the variable declaration)
+// together with types of the values assigned to them
+func assignedOperands(pkg *packages.Package, stmt ast.Stmt) ([]ast.Expr, []types.Type) {
+	var lhs []ast.Expr
+	var lhsTypes []types.Type
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		for i, operand := range stmt.Lhs {
+			lhs = append(lhs, operand)
+			lhsTypes = append(lhsTypes, assignedType(pkg, len(stmt.Lhs), stmt.Rhs, i))
+		}
+	case *ast.DeclStmt:
+		genDecl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.VAR {
+			break
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok || len(valueSpec.Values) == 0 {
+				continue
+			}
+			for i, name := range valueSpec.Names {
+				lhs = append(lhs, name)
+				lhsTypes = append(lhsTypes, assignedType(pkg, len(valueSpec.Names), valueSpec.Values, i))
+			}
+		}
+	}
+	return lhs, lhsTypes
+}
+
+// assignedType returns type of the value assigned to the i-th of lhsCount left hand side operands
+func assignedType(pkg *packages.Package, lhsCount int, rhs []ast.Expr, i int) types.Type {
+	if lhsCount == len(rhs) {
+		return pkg.TypesInfo.TypeOf(rhs[i])
+	}
+	if tuple, ok := pkg.TypesInfo.TypeOf(rhs[0]).(*types.Tuple); ok && i < tuple.Len() {
+		return tuple.At(i).Type()
+	}
+	return types.Typ[types.Invalid]
+}
+
+func usesVar(pkg *packages.Package, nodes []ast.Node, v *types.Var) bool {
+	used := false
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		ast.Inspect(node, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok && pkg.TypesInfo.Uses[ident] == v {
+				used = true
+			}
+			return !used
+		})
+	}
+	return used
+}
+
+// Related returns positions of the unchecked assignment and of the condition which was meant to guard it
+func (u UncheckedError) Related() []RelatedPosition {
+	assigned := "error assigned here is never checked"
+	if u.AssignedVar != nil {
+		assigned = fmt.Sprintf("error assigned to %v here is never checked", u.AssignedVar.Name())
+	}
+	return []RelatedPosition{
+		{Node: u.Assign, Message: assigned},
+		{Node: u.Cond, Message: fmt.Sprintf("condition checks %v instead of the error assigned before it", u.CheckedVar.Name())},
+	}
+}
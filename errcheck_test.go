@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type simpleUncheckedError struct {
+	AssignLine  int
+	CondLine    int
+	Checked     string
+	AssignedVar string
+}
+
+// findUncheckedErrors runs FindUncheckedError for every if statement of the module
+func findUncheckedErrors(t *testing.T, src string) []simpleUncheckedError {
+	dir, dispose, err := MustGenMod(src)
+	require.Nil(t, err)
+	defer dispose()
+
+	project, err := LoadPackage(dir)
+	require.Nil(t, err)
+	var found []simpleUncheckedError
+	for _, pkg := range project {
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(node ast.Node) bool {
+				blockStmt, ok := node.(*ast.BlockStmt)
+				if !ok {
+					return true
+				}
+				for i, stmt := range blockStmt.List {
+					ifStmt, ok := stmt.(*ast.IfStmt)
+					if !ok {
+						continue
+					}
+					unchecked, ok := FindUncheckedError(pkg, ifStmt, blockStmt.List[:i])
+					if !ok {
+						continue
+					}
+					simple := simpleUncheckedError{
+						AssignLine: pkg.Fset.Position(unchecked.Assign.Pos()).Line,
+						CondLine:   pkg.Fset.Position(unchecked.Cond.Pos()).Line,
+						Checked:    unchecked.CheckedVar.Name(),
+					}
+					if unchecked.AssignedVar != nil {
+						simple.AssignedVar = unchecked.AssignedVar.Name()
+					}
+					found = append(found, simple)
+				}
+				return true
+			})
+		}
+	}
+	return found
+}
+
+func TestFindUncheckedError(t *testing.T) {
+	t.Run("forgotten_errcheck_bug.go", func(t *testing.T) {
+		require.Equal(t, []simpleUncheckedError{
+			{AssignLine: 8, CondLine: 9, Checked: "err"},
+		}, findUncheckedErrors(t, loadExample(t)))
+	})
+	t.Run("duplicate_condition_bug.go", func(t *testing.T) {
+		require.Equal(t, []simpleUncheckedError{
+			{AssignLine: 10, CondLine: 11, Checked: "fErr", AssignedVar: "gErr"},
+		}, findUncheckedErrors(t, loadExample(t)))
+	})
+	t.Run("unrelated statement in between", func(t *testing.T) {
+		require.Equal(t, []simpleUncheckedError{
+			{AssignLine: 8, CondLine: 10, Checked: "err", AssignedVar: "err2"},
+		}, findUncheckedErrors(t, `package main
+
+func F(f func() error, g func() (int, error)) (int, error) {
+	err := f()
+	if err != nil {
+		return 0, err
+	}
+	_, err2 := g()
+	n := 1
+	if err != nil {
+		return n, err
+	}
+	return n, err2
+}
+
+func main() {}
+`))
+	})
+	t.Run("variable declaration", func(t *testing.T) {
+		require.Equal(t, []simpleUncheckedError{
+			{AssignLine: 8, CondLine: 9, Checked: "err", AssignedVar: "err2"},
+		}, findUncheckedErrors(t, `package main
+
+func F(f, g func() error) error {
+	err := f()
+	if err != nil {
+		return err
+	}
+	var err2 = g()
+	if err != nil {
+		return err
+	}
+	return err2
+}
+
+func main() {}
+`))
+	})
+	t.Run("checked assignment", func(t *testing.T) {
+		require.Empty(t, findUncheckedErrors(t, `package main
+
+func F(f func() error) error {
+	err := f()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func main() {}
+`))
+	})
+}
+
+type relatedLine struct {
+	Line    int
+	Message string
+}
+
+func analyzeRelated(t *testing.T, example string) [][]relatedLine {
+	dir, dispose := exampleModule(t, example)
+	defer dispose()
+
+	collect := &CollectReporting{}
+	analyzeModule(t, dir, collect)
+	var related [][]relatedLine
+	for _, info := range collect.Vanished {
+		var lines []relatedLine
+		for _, position := range info.Related {
+			lines = append(lines, relatedLine{Line: info.position(position.Node.Pos()).Line, Message: position.Message})
+		}
+		related = append(related, lines)
+	}
+	return related
+}
+
+func TestUncheckedErrorRelated(t *testing.T) {
+	t.Run("blank assignment", func(t *testing.T) {
+		require.Equal(t, [][]relatedLine{{
+			{Line: 8, Message: "error assigned here is never checked"},
+			{Line: 9, Message: "condition checks err instead of the error assigned before it"},
+		}}, analyzeRelated(t, "forgotten_errcheck_bug.go"))
+	})
+	t.Run("different variable", func(t *testing.T) {
+		require.Equal(t, [][]relatedLine{{
+			{Line: 10, Message: "error assigned to gErr here is never checked"},
+			{Line: 11, Message: "condition checks fErr instead of the error assigned before it"},
+		}}, analyzeRelated(t, "duplicate_condition_bug.go"))
+	})
+	t.Run("log output", func(t *testing.T) {
+		dir, dispose := exampleModule(t, "forgotten_errcheck_bug.go")
+		defer dispose()
+
+		out := bytes.NewBuffer(nil)
+		log.SetOutput(out)
+		defer log.SetOutput(os.Stderr)
+		analyzeModule(t, dir, LogReporting{})
+		require.Contains(t, out.String(), "\n\trelated: "+filepath.Join(dir, "main.go")+":8: error assigned here is never checked\n")
+		require.Contains(t, out.String(), "\n\trelated: "+filepath.Join(dir, "main.go")+":9: condition checks err instead of the error assigned before it\n")
+	})
+}
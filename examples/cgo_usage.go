@@ -0,0 +1,28 @@
+//go:build exclude
+
+package main
+
+// int add(int a, int b);
+import "C"
+
+func Sum(n int) int {
+	s := C.add(C.int(n), 1)
+	if s == 0 {
+		println("zero")
+	}
+	return int(s)
+}
+
+func NoErrCheck(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+	_ = w.Write(int(C.add(1, 2)))
+	if err != nil {
+		// this line removed by compiler even if file is rewritten by cgo
+		panic(err)
+	}
+}
+
+func main() { println(Sum(1)) }
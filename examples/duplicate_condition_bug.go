@@ -0,0 +1,20 @@
+//go:build exclude
+
+package main
+
+type Doer interface{ Do() error }
+
+func Handle(f, g Doer) error {
+	fErr := f.Do()
+	if fErr != nil {
+		return fErr
+	}
+	gErr := g.Do()
+	if fErr != nil {
+		// this line removed by compiler because fErr were already checked on line 9: -fix checks gErr instead
+		return gErr
+	}
+	return nil
+}
+
+func main() {}
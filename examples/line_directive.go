@@ -0,0 +1,19 @@
+//go:build exclude
+
+package main
+
+//line parser.rl:1
+func NoErrCheck(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+//line parser.rl:1
+	_ = w.Write(2)
+	if err != nil {
+		// both checks refer to the same template lines, so only the generated file positions can tell them apart
+		panic(err)
+	}
+}
+
+func main() {}
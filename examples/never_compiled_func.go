@@ -0,0 +1,28 @@
+//go:build exclude
+
+package main
+
+type List[T any] struct{ items []T }
+
+// methods of the generic type are compiled only for instantiated types
+func (l *List[T]) Push(v T) { l.items = append(l.items, v) }
+
+func Generic[V any](v V) V {
+	return v
+}
+
+func Used[V any](v V) V {
+	return v
+}
+
+// unused functions are still compiled and removed only by linker
+func unused(n int) int {
+	return n * 3
+}
+
+func _() {}
+
+// functions inlined into every caller still have own symbol
+func double(n int) int { return n * 2 }
+
+func main() { println(Used(1), double(len("ab"))) }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"log"
+	"os"
+	"slices"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// SuggestedFix is the mechanical edit of the source which most likely fixes the finding
+type SuggestedFix struct {
+	Message string
+	Edits   []TextEdit
+}
+
+// TextEdit replaces source in [Pos, End) with NewText
+type TextEdit struct {
+	Pos     token.Pos
+	End     token.Pos
+	NewText string
+}
+
+// SuggestDuplicateConditionFix suggests to check freshly assigned error variable instead of the one checked before:
+//
+//	gErr := G()
+//	if fErr != nil { // fErr were already checked before, but gErr is never checked
+func SuggestDuplicateConditionFix(unchecked UncheckedError) []SuggestedFix {
+	// error assigned to the blank identifier can't be checked without rewriting the assignment
+	if unchecked.AssignedVar == nil || unchecked.CheckedVar.Pos() > unchecked.Assign.Pos() {
+		return nil
+	}
+	return []SuggestedFix{{
+		Message: fmt.Sprintf("check %v instead of %v", unchecked.AssignedVar.Name(), unchecked.CheckedVar.Name()),
+		Edits:   []TextEdit{{Pos: unchecked.Checked.Pos(), End: unchecked.Checked.End(), NewText: unchecked.AssignedVar.Name()}},
+	}}
+}
+
+// FixReporting collects suggested fixes of the reported findings in order to apply them after analysis
+type FixReporting struct {
+	Reporting
+	fixed []VanishedInfo
+}
+
+func (r *FixReporting) ReportVanished(info VanishedInfo) {
+	r.Reporting.ReportVanished(info)
+	if len(info.SuggestedFixes) > 0 {
+		r.fixed = append(r.fixed, info)
+	}
+}
+
+func (r *FixReporting) Flush() error { return FlushReport(r.Reporting) }
+func (r *FixReporting) ReportAssembly(analysisPath string, project []*packages.Package, assembly Assembly) {
+	ReportAssembly(r.Reporting, analysisPath, project, assembly)
+}
+
+type fileEdit struct {
+	Start, End int
+	NewText    string
+}
+
+// Apply rewrites source files of the module with the first suggested fix of every collected finding
+// generated files, files outside of the analysis path (like cgo-rewritten sources) and files changed after analysis are never touched
+func (r *FixReporting) Apply() error {
+	edits := make(map[string][]fileEdit)
+	for _, info := range r.fixed {
+		filename := info.SourceFilename()
+		if info.Generated || !IsInsideDir(info.AnalysisPath, filename) {
+			continue
+		}
+		for _, edit := range info.SuggestedFixes[0].Edits {
+			start, end := info.Pkg.Fset.PositionFor(edit.Pos, false), info.Pkg.Fset.PositionFor(edit.End, false)
+			edits[filename] = append(edits[filename], fileEdit{Start: start.Offset, End: end.Offset, NewText: edit.NewText})
+		}
+	}
+	for filename, fileEdits := range edits {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		// offsets of the edits point into the content which was analyzed
+		loaded, err := LoadedSource(filename)
+		if err != nil || !bytes.Equal(content, loaded) {
+			log.Printf("skipped fixes of the file changed since analysis: file=[%v]", filename)
+			continue
+		}
+		// apply edits from the end of the file, so offsets of the remaining edits stay valid
+		slices.SortFunc(fileEdits, func(a, b fileEdit) int { return b.Start - a.Start })
+		applied, boundary := 0, len(content)
+		for _, edit := range fileEdits {
+			if edit.End > boundary {
+				log.Printf("skipped overlapping fix: file=[%v], offset=[%v]", filename, edit.Start)
+				continue
+			}
+			content = slices.Concat(content[:edit.Start], []byte(edit.NewText), content[edit.End:])
+			applied, boundary = applied+1, edit.Start
+		}
+		if err := os.WriteFile(filename, content, stat.Mode()); err != nil {
+			return err
+		}
+		log.Printf("applied %v fixes: file=[%v]", applied, filename)
+	}
+	return nil
+}
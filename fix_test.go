@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func analyzeFixes(t *testing.T, dir string) *FixReporting {
+	fixes := &FixReporting{Reporting: &CollectReporting{}}
+	analyzeModule(t, dir, fixes)
+	return fixes
+}
+
+func TestSuggestDuplicateConditionFix(t *testing.T) {
+	t.Run("duplicate condition", func(t *testing.T) {
+		dir, dispose := exampleModule(t, "duplicate_condition_bug.go")
+		defer dispose()
+
+		fixes := analyzeFixes(t, dir)
+		require.Len(t, fixes.fixed, 1)
+		require.Equal(t, "check gErr instead of fErr", fixes.fixed[0].SuggestedFixes[0].Message)
+		require.Nil(t, fixes.Apply())
+
+		fixed, err := os.ReadFile(filepath.Join(dir, "main.go"))
+		require.Nil(t, err)
+		require.Contains(t, string(fixed), "gErr := g.Do()\n\tif gErr != nil {")
+		require.Empty(t, analyzeFixes(t, dir).fixed)
+	})
+	t.Run("forgotten check without variable", func(t *testing.T) {
+		dir, dispose := exampleModule(t, "forgotten_errcheck_bug.go")
+		defer dispose()
+		require.Empty(t, analyzeFixes(t, dir).fixed)
+	})
+	t.Run("file changed after analysis", func(t *testing.T) {
+		dir, dispose := exampleModule(t, "duplicate_condition_bug.go")
+		defer dispose()
+
+		fixes := analyzeFixes(t, dir)
+		require.Len(t, fixes.fixed, 1)
+		mainPath := filepath.Join(dir, "main.go")
+		src, err := os.ReadFile(mainPath)
+		require.Nil(t, err)
+		changed := strings.Replace(string(src), "package main\n", "package main\n\n// edited after analysis\n", 1)
+		require.Nil(t, os.WriteFile(mainPath, []byte(changed), 0o644))
+		require.Nil(t, fixes.Apply())
+
+		content, err := os.ReadFile(mainPath)
+		require.Nil(t, err)
+		require.Equal(t, changed, string(content))
+	})
+}
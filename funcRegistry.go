@@ -21,6 +21,10 @@ func analyzeFunc(pkg *packages.Package, funcDecl *ast.FuncDecl) FuncProps {
 			return true
 		}
 		static, dynamic := 0, 0
+		// bare return of named results returns values assigned anywhere in the function (cgo wrappers fill them from C side)
+		if len(returnStmt.Results) == 0 && funcDecl.Type.Results.NumFields() > 0 {
+			dynamic++
+		}
 		for _, result := range returnStmt.Results {
 			typeAndValue, ok := pkg.TypesInfo.Types[result]
 			if ok && (typeAndValue.Value != nil || typeAndValue.IsNil()) {
@@ -63,4 +63,17 @@ func main() {}
 		require.Nil(t, err)
 		require.Equal(t, FuncProps{DeterministicReturn: true}, analyzeFunc(project[0], MustExtractFunc(project)))
 	})
+	t.Run("named results return", func(t *testing.T) {
+		dir, dispose, err := MustGenMod(`package main
+func Read(n int) (r int) {
+	r = n * 2
+	return
+}
+func main() {}
+`)
+		defer dispose()
+		project, err := LoadPackage(dir)
+		require.Nil(t, err)
+		require.Equal(t, FuncProps{DeterministicReturn: false}, analyzeFunc(project[0], MustExtractFunc(project)))
+	})
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end"`
+}
+
+// GitLabReporting writes findings as GitLab Code Quality report (JSON array of issues) on Flush
+type GitLabReporting struct {
+	Out io.Writer // os.Stdout if nil
+
+	findings []VanishedInfo
+}
+
+func (r *GitLabReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func gitlabCheck(info VanishedInfo) (checkName, severity string) {
+	rule, _ := findingRule(info)
+	switch info.FuncReason {
+	case FuncNeverCompiled, FuncDroppedByLinker:
+		return "govanish/" + rule, "minor"
+	}
+	return "govanish/" + rule, "major"
+}
+
+// GitLabFingerprint identifies finding across commits: it depends on package, function and normalized snippet, but not on the position
+// identical snippets of the same function are distinguished by the occurrence number
+func GitLabFingerprint(checkName string, info VanishedInfo, occurrence int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v\n%v\n%v", checkName, SnippetKey(info), occurrence)))
+	return hex.EncodeToString(hash[:16])
+}
+
+func (r *GitLabReporting) Flush() error {
+	findings := r.findings
+	r.findings = nil
+	sortByPackage(findings)
+	issues := make([]gitlabIssue, 0, len(findings))
+	occurrences := make(map[string]int)
+	for _, info := range findings {
+		checkName, severity := gitlabCheck(info)
+		key := checkName + "\n" + SnippetKey(info)
+		occurrence := occurrences[key]
+		occurrences[key]++
+
+		start, end := info.Region()
+		description := fmt.Sprintf("func %v: seems like %v", info.FuncName, vanishedMessage(info, "code"))
+		for _, related := range info.Related {
+			relatedStart, _ := info.RelatedRange(related)
+			description += fmt.Sprintf("; %v:%v: %v", reportPath(info, relatedStart.Filename), relatedStart.Line, related.Message)
+		}
+		issues = append(issues, gitlabIssue{
+			Description: description,
+			CheckName:   checkName,
+			Fingerprint: GitLabFingerprint(checkName, info, occurrence),
+			Severity:    severity,
+			Location: gitlabLocation{
+				Path:  reportPath(info, start.Filename),
+				Lines: gitlabLines{Begin: start.Line, End: end.Line},
+			},
+		})
+	}
+	return writeJson(r.Out, issues)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitLabReporting(t *testing.T) {
+	dir, dispose := exampleModule(t, "forgotten_errcheck_bug.go")
+	defer dispose()
+
+	report := func() []gitlabIssue {
+		out := bytes.NewBuffer(nil)
+		reporting := &GitLabReporting{Out: out}
+		analyzeModule(t, dir, reporting)
+		require.Nil(t, FlushReport(reporting))
+		var issues []gitlabIssue
+		require.Nil(t, json.Unmarshal(out.Bytes(), &issues))
+		return issues
+	}
+	issues := report()
+	require.Len(t, issues, 1)
+	require.Equal(t, "govanish/vanished-code", issues[0].CheckName)
+	require.Equal(t, "major", issues[0].Severity)
+	require.Equal(t, gitlabLocation{Path: "main.go", Lines: gitlabLines{Begin: 11, End: 11}}, issues[0].Location)
+	require.Equal(t, "func NoErrCheck: seems like code vanished from compiled binary; main.go:8: error assigned here is never checked; main.go:9: condition checks err instead of the error assigned before it", issues[0].Description)
+
+	// unrelated edits which shift lines must keep the fingerprint
+	mainPath := filepath.Join(dir, "main.go")
+	src, err := os.ReadFile(mainPath)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(mainPath, []byte(strings.Replace(string(src), "package main\n", "package main\n\n// Writer is checked for errors\n", 1)), 0o644))
+	shifted := report()
+	require.Len(t, shifted, 1)
+	require.Equal(t, 13, shifted[0].Location.Lines.Begin)
+	require.Equal(t, issues[0].Fingerprint, shifted[0].Fingerprint)
+
+	out := bytes.NewBuffer(nil)
+	require.Nil(t, (&GitLabReporting{Out: out}).Flush())
+	require.Equal(t, "[]\n", out.String())
+}
+
+func TestGitLabReportingEdgeCases(t *testing.T) {
+	report := func(t *testing.T, analyze func(Reporting)) []gitlabIssue {
+		out := bytes.NewBuffer(nil)
+		analyze(&GitLabReporting{Out: out})
+		var issues []gitlabIssue
+		require.Nil(t, json.Unmarshal(out.Bytes(), &issues))
+		return issues
+	}
+	t.Run("several files and packages", func(t *testing.T) {
+		issues := report(t, func(r Reporting) { reportEdgeCases(t, r) })
+		var paths []string
+		fingerprints := make(Set)
+		for _, issue := range issues {
+			paths = append(paths, issue.Location.Path)
+			fingerprints[issue.Fingerprint] = struct{}{}
+		}
+		require.Equal(t, []string{"main.go", "other.go", "lib/lib.go"}, paths)
+		require.Len(t, fingerprints, 3)
+	})
+	t.Run("methods with the same name", func(t *testing.T) {
+		method := func(receiver string) string {
+			return "func (r *" + receiver + ") Close() {\n\terr := r.w.Write(1)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n" +
+				"\t_ = r.w.Write(2)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n}\n\n"
+		}
+		types := "package main\n\ntype A struct{ w interface{ Write(n int) error } }\n\ntype B struct{ w interface{ Write(n int) error } }\n\n"
+		dir, dispose, err := MustGenMod(types + method("A") + method("B") + "func main() {}\n")
+		require.Nil(t, err)
+		defer dispose()
+		analyze := func(r Reporting) {
+			analyzeModule(t, dir, r)
+			require.Nil(t, FlushReport(r))
+		}
+
+		issues := report(t, analyze)
+		require.Len(t, issues, 2)
+		require.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+		// snippets are identical, so without the receiver type fingerprint of B would be taken from the occurrence number
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(types+method("B")+"func main() {}\n"), 0o644))
+		fixed := report(t, analyze)
+		require.Len(t, fixed, 1)
+		require.Equal(t, issues[1].Fingerprint, fixed[0].Fingerprint)
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &GitLabReporting{Out: out})
+		require.Equal(t, "[]\n", out.String())
+	})
+}
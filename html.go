@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AssemblyReporting is implemented by reportings which render the compiled code together with findings
+type AssemblyReporting interface {
+	Reporting
+	ReportAssembly(analysisPath string, project []*packages.Package, assembly Assembly)
+}
+
+// ReportAssembly passes analyzed packages and their assembly to the reporting (if it renders them at all)
+func ReportAssembly(reporting Reporting, analysisPath string, project []*packages.Package, assembly Assembly) {
+	if assemblyReporting, ok := reporting.(AssemblyReporting); ok {
+		assemblyReporting.ReportAssembly(analysisPath, project, assembly)
+	}
+}
+
+// HtmlReporting renders self-contained page with module packages and their sources on Flush:
+// vanished lines are highlighted and surviving lines are annotated with the number of instructions
+type HtmlReporting struct {
+	Out io.Writer // os.Stdout if nil
+
+	analysisPath string
+	project      []*packages.Package
+	assembly     Assembly
+	findings     []VanishedInfo
+}
+
+func (r *HtmlReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func (r *HtmlReporting) ReportAssembly(analysisPath string, project []*packages.Package, assembly Assembly) {
+	r.analysisPath, r.project, r.assembly = analysisPath, project, assembly
+}
+
+type htmlLine struct {
+	Number       int
+	Instructions int
+	Code         string
+	Vanished     bool
+	Messages     []string // findings which end at this line
+}
+
+type htmlFile struct {
+	ID       string
+	Path     string
+	Findings int
+	Lines    []htmlLine
+
+	pkg *htmlPackage
+}
+
+type htmlPackage struct {
+	ID       string
+	Path     string
+	Findings int
+	Files    []*htmlFile
+}
+
+type htmlReport struct {
+	Module   string
+	Findings int
+	Packages []*htmlPackage
+}
+
+// Flush writes the page: without ReportAssembly (e.g. in compare mode) only files with findings are rendered
+func (r *HtmlReporting) Flush() error {
+	out := r.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	report := htmlReport{Module: r.analysisPath, Findings: len(r.findings)}
+	if report.Module == "" && len(r.findings) > 0 {
+		report.Module = r.findings[0].AnalysisPath
+	}
+	packagesByPath := make(map[string]*htmlPackage)
+	filesByName := make(map[string]*htmlFile)
+	addFile := func(pkgPath, filename string) *htmlFile {
+		if file, ok := filesByName[filename]; ok {
+			return file
+		}
+		pkg, ok := packagesByPath[pkgPath]
+		if !ok {
+			pkg = &htmlPackage{ID: fmt.Sprintf("package-%v", len(packagesByPath)), Path: pkgPath}
+			packagesByPath[pkgPath] = pkg
+			report.Packages = append(report.Packages, pkg)
+		}
+		relativePath, err := filepath.Rel(report.Module, filename)
+		if err != nil {
+			relativePath = filename
+		}
+		file := &htmlFile{ID: fmt.Sprintf("file-%v", len(filesByName)), Path: filepath.ToSlash(relativePath), pkg: pkg}
+		filesByName[filename] = file
+		pkg.Files = append(pkg.Files, file)
+		content, err := LoadedSource(filename)
+		if err != nil {
+			return file
+		}
+		for i, code := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			file.Lines = append(file.Lines, htmlLine{
+				Number:       i + 1,
+				Instructions: r.assembly.Instructions[filename][i+1],
+				Code:         strings.TrimSuffix(code, "\r"),
+			})
+		}
+		return file
+	}
+	for _, pkg := range r.project {
+		for _, filename := range pkg.GoFiles {
+			if IsInsideDir(r.analysisPath, filename) {
+				addFile(pkg.PkgPath, filename)
+			}
+		}
+	}
+	for _, info := range r.findings {
+		start, end := info.Region()
+		file := addFile(info.Pkg.PkgPath, start.Filename)
+		file.Findings++
+		file.pkg.Findings++
+		for line := start.Line; line <= end.Line && line <= len(file.Lines); line++ {
+			file.Lines[line-1].Vanished = true
+		}
+		if end.Line <= len(file.Lines) {
+			message := fmt.Sprintf("func %v: %v", info.FuncName, vanishedMessage(info, "code"))
+			for _, related := range info.Related {
+				relatedStart, _ := info.RelatedRange(related)
+				message += fmt.Sprintf("; line %v: %v", relatedStart.Line, related.Message)
+			}
+			file.Lines[end.Line-1].Messages = append(file.Lines[end.Line-1].Messages, message)
+		}
+	}
+	slices.SortFunc(report.Packages, func(a, b *htmlPackage) int { return strings.Compare(a.Path, b.Path) })
+	for _, pkg := range report.Packages {
+		slices.SortFunc(pkg.Files, func(a, b *htmlFile) int { return strings.Compare(a.Path, b.Path) })
+	}
+	r.findings = nil
+	return htmlReportTemplate.Execute(out, report)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>govanish report: {{.Module}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #24292f; }
+table.packages { border-collapse: collapse; margin-bottom: 2em; }
+table.packages td, table.packages th { padding: 2px 12px; text-align: left; border-bottom: 1px solid #d0d7de; }
+td.count { text-align: right; }
+tr.has-findings td { font-weight: bold; color: #cf222e; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; font-family: monospace; }
+table.source { border-collapse: collapse; font-family: monospace; font-size: 13px; width: 100%; }
+table.source td { padding: 0 8px; white-space: pre; vertical-align: top; }
+td.line { color: #6e7781; text-align: right; user-select: none; }
+td.instructions { color: #1a7f37; text-align: right; user-select: none; }
+tr.vanished td { background: #ffebe9; }
+tr.vanished td.code { color: #cf222e; }
+tr.message td { background: #fff8c5; white-space: normal; font-family: sans-serif; }
+</style>
+</head>
+<body>
+<h1>govanish report</h1>
+<p>Module: <code>{{.Module}}</code>, findings: {{.Findings}}. Green numbers are instructions generated for the line, red lines vanished from the compiled binary.</p>
+<table class="packages">
+<tr><th>package</th><th>files</th><th>findings</th></tr>
+{{- range .Packages}}
+<tr{{if .Findings}} class="has-findings"{{end}}><td><a href="#{{.ID}}">{{.Path}}</a></td><td class="count">{{len .Files}}</td><td class="count">{{.Findings}}</td></tr>
+{{- end}}
+</table>
+{{- range .Packages}}
+<h2 id="{{.ID}}">{{.Path}}</h2>
+{{- range .Files}}
+<details id="{{.ID}}"{{if .Findings}} open{{end}}>
+<summary>{{.Path}} (findings: {{.Findings}})</summary>
+<table class="source">
+{{- range .Lines}}
+<tr{{if .Vanished}} class="vanished"{{end}}><td class="line">{{.Number}}</td><td class="instructions"{{if .Instructions}} title="{{.Instructions}} instructions"{{end}}>{{if .Instructions}}{{.Instructions}}{{end}}</td><td class="code">{{.Code}}</td></tr>
+{{- range .Messages}}
+<tr class="message"><td></td><td></td><td>{{.}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+</details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
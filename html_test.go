@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHtmlReporting(t *testing.T) {
+	dir, dispose := exampleModule(t, "forgotten_errcheck_bug.go")
+	defer dispose()
+
+	out := bytes.NewBuffer(nil)
+	reporting := &HtmlReporting{Out: out}
+	project, assembly := analyzeModule(t, dir, reporting)
+	ReportAssembly(reporting, dir, project, assembly)
+	require.Nil(t, FlushReport(reporting))
+
+	page := out.String()
+	require.Contains(t, page, `<summary>main.go (findings: 1)</summary>`)
+	require.Contains(t, page, `<tr class="vanished"><td class="line">11</td><td class="instructions"></td><td class="code">		panic(err)</td></tr>`)
+	require.Contains(t, page, "func NoErrCheck: code vanished from compiled binary; line 8: error assigned here is never checked")
+	// surviving lines are annotated with the number of instructions
+	require.Regexp(t, `<tr><td class="line">8</td><td class="instructions" title="\d+ instructions">\d+</td><td class="code">	_ = w.Write\(2\)</td></tr>`, page)
+	require.Contains(t, page, "didn&#39;t changed")
+}
+
+func TestHtmlReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &HtmlReporting{Out: out})
+
+		page := out.String()
+		require.Contains(t, page, "findings: 3.")
+		require.Regexp(t, `<tr class="has-findings"><td><a href="#package-0">[^<]+</a></td><td class="count">2</td><td class="count">2</td></tr>`, page)
+		require.Regexp(t, `<tr class="has-findings"><td><a href="#package-1">[^<]+/lib</a></td><td class="count">1</td><td class="count">1</td></tr>`, page)
+		require.Contains(t, page, `<summary>main.go (findings: 1)</summary>`)
+		require.Contains(t, page, `<summary>other.go (findings: 1)</summary>`)
+		require.Contains(t, page, `<summary>lib/lib.go (findings: 1)</summary>`)
+	})
+	t.Run("escaping", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &HtmlReporting{Out: out})
+		require.Contains(t, out.String(), `<td class="code">		panic(&#34;&lt;&#34; &#43; err.Error() &#43; &#34;&gt; &amp; \&#34;quoted\&#34;&#34;)</td>`)
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &HtmlReporting{Out: out})
+		require.Contains(t, out.String(), "findings: 0.")
+		require.NotContains(t, out.String(), `class="vanished"`)
+	})
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sortByPackage orders findings by package, file and line, so reports group them by package
+func sortByPackage(findings []VanishedInfo) {
+	slices.SortStableFunc(findings, func(a, b VanishedInfo) int {
+		if c := strings.Compare(a.Pkg.PkgPath, b.Pkg.PkgPath); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Filename(), b.Filename()); c != 0 {
+			return c
+		}
+		return a.StartLine() - b.StartLine()
+	})
+}
+
+func reportPath(info VanishedInfo, filename string) string {
+	relativePath, err := filepath.Rel(info.AnalysisPath, filename)
+	if err != nil {
+		return filename
+	}
+	return filepath.ToSlash(relativePath)
+}
+
+// findingRule identifies the kind of the finding in machine-readable reports
+func findingRule(info VanishedInfo) (id, description string) {
+	switch info.FuncReason {
+	case FuncNeverCompiled:
+		return "func-never-compiled", "function was never compiled"
+	case FuncDroppedByLinker:
+		return "method-dropped-by-linker", "method is dropped by linker"
+	}
+	return "vanished-code", "code vanished from compiled binary"
+}
+
+func writeJson(out io.Writer, report any) error {
+	if out == nil {
+		out = os.Stdout
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type jsonLocation struct {
+	Path        string `json:"path"`
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+}
+
+type jsonRelated struct {
+	Location jsonLocation `json:"location"`
+	Message  string       `json:"message"`
+}
+
+type jsonFinding struct {
+	Rule           string        `json:"rule"`
+	Package        string        `json:"package"`
+	Func           string        `json:"func"`
+	Message        string        `json:"message"`
+	Location       jsonLocation  `json:"location"`
+	Snippet        string        `json:"snippet"`
+	Generated      bool          `json:"generated,omitempty"`
+	LinkedIn       []string      `json:"linkedIn,omitempty"`
+	DroppedFrom    []string      `json:"droppedFrom,omitempty"`
+	Related        []jsonRelated `json:"related,omitempty"`
+	SuggestedFixes []string      `json:"suggestedFixes,omitempty"`
+}
+
+func newJsonLocation(info VanishedInfo, start, end token.Position) jsonLocation {
+	return jsonLocation{
+		Path:        reportPath(info, start.Filename),
+		StartLine:   start.Line,
+		StartColumn: start.Column,
+		EndLine:     end.Line,
+		EndColumn:   end.Column,
+	}
+}
+
+// JsonReporting writes findings as JSON array on Flush: paths are relative to the module root
+type JsonReporting struct {
+	Out io.Writer // os.Stdout if nil
+
+	findings []VanishedInfo
+}
+
+func (r *JsonReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func (r *JsonReporting) Flush() error {
+	findings := r.findings
+	r.findings = nil
+	sortByPackage(findings)
+	report := make([]jsonFinding, 0, len(findings))
+	for _, info := range findings {
+		rule, _ := findingRule(info)
+		start, end := info.Region()
+		finding := jsonFinding{
+			Rule:        rule,
+			Package:     info.Pkg.PkgPath,
+			Func:        info.FuncName,
+			Message:     "seems like " + vanishedMessage(info, "code"),
+			Location:    newJsonLocation(info, start, end),
+			Snippet:     info.Snippet(),
+			Generated:   info.Generated,
+			LinkedIn:    info.LinkedIn,
+			DroppedFrom: info.DroppedFrom,
+		}
+		for _, related := range info.Related {
+			relatedStart, relatedEnd := info.RelatedRange(related)
+			finding.Related = append(finding.Related, jsonRelated{Location: newJsonLocation(info, relatedStart, relatedEnd), Message: related.Message})
+		}
+		for _, fix := range info.SuggestedFixes {
+			finding.SuggestedFixes = append(finding.SuggestedFixes, fix.Message)
+		}
+		report = append(report, finding)
+	}
+	return writeJson(r.Out, report)
+}
+
+type sarifReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func newSarifLocation(info VanishedInfo, start, end token.Position) sarifLocation {
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: reportPath(info, start.Filename), URIBaseID: "%SRCROOT%"},
+		Region:           sarifRegion{StartLine: start.Line, StartColumn: start.Column, EndLine: end.Line, EndColumn: end.Column},
+	}}
+}
+
+// SarifReporting writes findings as SARIF 2.1.0 log on Flush: related positions are written as relatedLocations
+type SarifReporting struct {
+	Out io.Writer // os.Stdout if nil
+
+	findings []VanishedInfo
+}
+
+func (r *SarifReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func (r *SarifReporting) Flush() error {
+	findings := r.findings
+	r.findings = nil
+	sortByPackage(findings)
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "govanish", InformationUri: "https://github.com/sivukhin/govanish", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	ruleIndex := make(map[string]int)
+	for _, info := range findings {
+		rule, description := findingRule(info)
+		index, ok := ruleIndex[rule]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[rule] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: rule, ShortDescription: sarifMessage{Text: description}})
+		}
+		start, end := info.Region()
+		result := sarifResult{
+			RuleID:    rule,
+			RuleIndex: index,
+			Level:     "warning",
+			Message:   sarifMessage{Text: "func " + info.FuncName + ": seems like " + vanishedMessage(info, "code")},
+			Locations: []sarifLocation{newSarifLocation(info, start, end)},
+		}
+		for i, related := range info.Related {
+			relatedStart, relatedEnd := info.RelatedRange(related)
+			location := newSarifLocation(info, relatedStart, relatedEnd)
+			location.ID = &i
+			location.Message = &sarifMessage{Text: related.Message}
+			result.RelatedLocations = append(result.RelatedLocations, location)
+		}
+		run.Results = append(run.Results, result)
+	}
+	return writeJson(r.Out, sarifReport{Schema: "https://json.schemastore.org/sarif-2.1.0.json", Version: "2.1.0", Runs: []sarifRun{run}})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonReporting(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	reportExample(t, "forgotten_errcheck_bug.go", &JsonReporting{Out: out})
+
+	var findings []jsonFinding
+	require.Nil(t, json.Unmarshal(out.Bytes(), &findings))
+	require.Len(t, findings, 1)
+	require.Equal(t, "vanished-code", findings[0].Rule)
+	require.Equal(t, "NoErrCheck", findings[0].Func)
+	require.Equal(t, "seems like code vanished from compiled binary", findings[0].Message)
+	require.Equal(t, jsonLocation{Path: "main.go", StartLine: 11, StartColumn: 3, EndLine: 11, EndColumn: 13}, findings[0].Location)
+	require.Equal(t, "panic(err)", findings[0].Snippet)
+	require.Equal(t, []jsonRelated{
+		{Location: jsonLocation{Path: "main.go", StartLine: 8, StartColumn: 2, EndLine: 8, EndColumn: 16}, Message: "error assigned here is never checked"},
+		{Location: jsonLocation{Path: "main.go", StartLine: 9, StartColumn: 5, EndLine: 9, EndColumn: 15}, Message: "condition checks err instead of the error assigned before it"},
+	}, findings[0].Related)
+
+	out.Reset()
+	require.Nil(t, (&JsonReporting{Out: out}).Flush())
+	require.Equal(t, "[]\n", out.String())
+}
+
+func TestJsonReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &JsonReporting{Out: out})
+
+		var findings []jsonFinding
+		require.Nil(t, json.Unmarshal(out.Bytes(), &findings))
+		require.Len(t, findings, 3)
+		paths := make([]string, 0, len(findings))
+		for _, finding := range findings {
+			paths = append(paths, finding.Location.Path)
+		}
+		require.Equal(t, []string{"main.go", "other.go", "lib/lib.go"}, paths)
+		require.Equal(t, findings[0].Package+"/lib", findings[2].Package)
+		require.Equal(t, []string{"Quoted", "Other", "Lib"}, []string{findings[0].Func, findings[1].Func, findings[2].Func})
+	})
+	t.Run("escaping", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &JsonReporting{Out: out})
+
+		require.Contains(t, out.String(), `"snippet": "panic(\"\u003c\" + err.Error() + \"\u003e \u0026 \\\"quoted\\\"\")"`)
+		var findings []jsonFinding
+		require.Nil(t, json.Unmarshal(out.Bytes(), &findings))
+		require.Equal(t, `panic("<" + err.Error() + "> & \"quoted\"")`, findings[0].Snippet)
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &JsonReporting{Out: out})
+		require.Equal(t, "[]\n", out.String())
+	})
+}
+
+func TestSarifReporting(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	reportExample(t, "forgotten_errcheck_bug.go", &SarifReporting{Out: out})
+
+	var report sarifReport
+	require.Nil(t, json.Unmarshal(out.Bytes(), &report))
+	require.Equal(t, "2.1.0", report.Version)
+	require.Len(t, report.Runs, 1)
+	require.Equal(t, []sarifRule{{ID: "vanished-code", ShortDescription: sarifMessage{Text: "code vanished from compiled binary"}}}, report.Runs[0].Tool.Driver.Rules)
+	require.Len(t, report.Runs[0].Results, 1)
+	result := report.Runs[0].Results[0]
+	require.Equal(t, "vanished-code", result.RuleID)
+	require.Equal(t, "func NoErrCheck: seems like code vanished from compiled binary", result.Message.Text)
+	location := func(id, startLine, startColumn, endColumn int, message string) sarifLocation {
+		return sarifLocation{
+			ID: &id,
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "main.go", URIBaseID: "%SRCROOT%"},
+				Region:           sarifRegion{StartLine: startLine, StartColumn: startColumn, EndLine: startLine, EndColumn: endColumn},
+			},
+			Message: &sarifMessage{Text: message},
+		}
+	}
+	require.Equal(t, sarifRegion{StartLine: 11, StartColumn: 3, EndLine: 11, EndColumn: 13}, result.Locations[0].PhysicalLocation.Region)
+	require.Equal(t, []sarifLocation{
+		location(0, 8, 2, 16, "error assigned here is never checked"),
+		location(1, 9, 5, 15, "condition checks err instead of the error assigned before it"),
+	}, result.RelatedLocations)
+}
+
+func TestSarifReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &SarifReporting{Out: out})
+
+		var report sarifReport
+		require.Nil(t, json.Unmarshal(out.Bytes(), &report))
+		require.Len(t, report.Runs[0].Tool.Driver.Rules, 1)
+		var uris, messages []string
+		for _, result := range report.Runs[0].Results {
+			uris = append(uris, result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+			messages = append(messages, result.Message.Text)
+		}
+		require.Equal(t, []string{"main.go", "other.go", "lib/lib.go"}, uris)
+		require.Equal(t, []string{
+			"func Quoted: seems like code vanished from compiled binary",
+			"func Other: seems like code vanished from compiled binary",
+			"func Lib: seems like code vanished from compiled binary",
+		}, messages)
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &SarifReporting{Out: out})
+
+		// SARIF requires arrays even for the empty run
+		require.Contains(t, out.String(), `"rules": []`)
+		require.Contains(t, out.String(), `"results": []`)
+		var report sarifReport
+		require.Nil(t, json.Unmarshal(out.Bytes(), &report))
+		require.Len(t, report.Runs, 1)
+		require.Empty(t, report.Runs[0].Results)
+	})
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LinkedBinary is the binary of the single main package of the module
+type LinkedBinary struct {
+	MainPackage string // directory of the main package relative to the module root (cmd/api)
+	Symbols     Set    // text symbols with type arguments stripped (pkg.Map[go.shape.int] -> pkg.Map)
+}
+
+// LinkMainBinaries builds every main package of the module separately, because different binaries
+// keep different methods and instantiate generics differently
+// inlining is disabled, so every reachable function keeps its own symbol in the binary
+func LinkMainBinaries(path string, config BuildConfig) ([]LinkedBinary, error) {
+	pkgs, err := ListModulePackages(path, config)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "govanish-bin-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	var binaries []LinkedBinary
+	for _, pkg := range pkgs {
+		if pkg.Name != "main" {
+			continue
+		}
+		binary := filepath.Join(dir, fmt.Sprintf("main-%v", len(binaries)))
+		args := append([]string{"build", "-C", path, "-o", binary}, config.Flags...)
+		for _, gcflags := range NoInlineGcflags(config.Gcflags) {
+			args = append(args, "-gcflags", gcflags)
+		}
+		cmd := config.Command(append(args, pkg.ImportPath)...)
+		stderr := bytes.NewBuffer(nil)
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf(`go build failed: err=%w, cmd="%v", stderr=%v`, err, cmd, strings.TrimSpace(stderr.String()))
+		}
+		symbols, err := ListTextSymbols(binary, config)
+		if err != nil {
+			return nil, err
+		}
+		mainPackage, err := filepath.Rel(path, pkg.Dir)
+		if err != nil {
+			mainPackage = pkg.ImportPath
+		}
+		binaries = append(binaries, LinkedBinary{MainPackage: filepath.ToSlash(mainPackage), Symbols: symbols})
+	}
+	return binaries, nil
+}
+
+// NoInlineGcflags adds -l to the every user -gcflags value, because go command applies only the last value which pattern matches the package
+func NoInlineGcflags(gcflags []string) []string {
+	merged := []string{"all=-l"}
+	for _, value := range gcflags {
+		pattern, flags := "", value
+		if i := strings.Index(value, "="); i >= 0 && !strings.HasPrefix(value, "-") {
+			pattern, flags = value[:i+1], value[i+1:]
+		}
+		merged = append(merged, pattern+strings.TrimSpace(flags+" -l"))
+	}
+	return merged
+}
+
+// ListTextSymbols returns names of the functions linked into the binary (T and t symbols of go tool nm)
+func ListTextSymbols(binary string, config BuildConfig) (Set, error) {
+	cmd := config.Command("tool", "nm", binary)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(`go tool nm failed: err=%w, cmd="%v", stderr=%v`, err, cmd, strings.TrimSpace(stderr.String()))
+	}
+	return ParseTextSymbols(bufio.NewScanner(bytes.NewReader(output))), nil
+}
+
+// ParseTextSymbols parses go tool nm output lines: "  4a3b20 T example.com/module/internal/store.(*Store).Get"
+func ParseTextSymbols(scanner *bufio.Scanner) Set {
+	symbols := make(Set)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// undefined symbols has no address and symbol names can contain spaces (type:struct { ... })
+		if len(fields) < 3 || (fields[1] != "T" && fields[1] != "t") {
+			continue
+		}
+		symbols[StripTypeArguments(strings.Join(fields[2:], " "))] = struct{}{}
+	}
+	return symbols
+}
+
+// StripTypeArguments removes instantiation of generics from the symbol name: pkg.(*List[go.shape.int]).Push -> pkg.(*List).Push
+func StripTypeArguments(symbol string) string {
+	if !strings.Contains(symbol, "[") {
+		return symbol
+	}
+	var stripped strings.Builder
+	depth := 0
+	for _, c := range symbol {
+		switch {
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			stripped.WriteRune(c)
+		}
+	}
+	return stripped.String()
+}
+
+func IsInternalPackage(pkgPath string) bool {
+	return strings.HasPrefix(pkgPath, "internal/") || strings.Contains(pkgPath, "/internal/") || strings.HasSuffix(pkgPath, "/internal")
+}
+
+// MethodSymbols returns linker symbol names of the method: value receiver methods also has pointer wrapper
+func MethodSymbols(pkgPath string, funcDecl *ast.FuncDecl) []string {
+	receiver := funcDecl.Recv.List[0].Type
+	pointer := false
+	if star, ok := receiver.(*ast.StarExpr); ok {
+		receiver, pointer = star.X, true
+	}
+	// symbols of the generic types are matched with type arguments stripped
+	if index, ok := receiver.(*ast.IndexExpr); ok {
+		receiver = index.X
+	} else if index, ok := receiver.(*ast.IndexListExpr); ok {
+		receiver = index.X
+	}
+	ident, ok := receiver.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if pointer {
+		return []string{fmt.Sprintf("%v.(*%v).%v", pkgPath, ident.Name, funcDecl.Name.Name)}
+	}
+	return []string{
+		fmt.Sprintf("%v.%v.%v", pkgPath, ident.Name, funcDecl.Name.Name),
+		fmt.Sprintf("%v.(*%v).%v", pkgPath, ident.Name, funcDecl.Name.Name),
+	}
+}
+
+// AnalyzeUnusedMethods reports exported methods of the internal packages which linker dropped from every main binary of the module
+// method unreachable both from direct calls and through interfaces is a common sign of stale interface implementation
+// with perBinary methods dropped only from some of the binaries are reported too
+func AnalyzeUnusedMethods(
+	analysisPath string,
+	project []*packages.Package,
+	config BuildConfig,
+	perBinary bool,
+	generated GeneratedFiles,
+	reporting Reporting,
+) error {
+	binaries, err := LinkMainBinaries(analysisPath, config)
+	if err != nil {
+		return err
+	}
+	if len(binaries) == 0 {
+		log.Printf("module has no main packages - unused methods analysis skipped")
+		return nil
+	}
+	log.Printf("linked %v main packages", len(binaries))
+	for _, pkg := range project {
+		if !IsInternalPackage(pkg.PkgPath) {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			isGenerated, skip := generated.Classify(analysisPath, pkg, file)
+			if skip {
+				continue
+			}
+			for _, decl := range file.Decls {
+				funcDecl, ok := decl.(*ast.FuncDecl)
+				if !ok || funcDecl.Recv == nil || funcDecl.Body == nil || !funcDecl.Name.IsExported() {
+					continue
+				}
+				symbols := MethodSymbols(pkg.PkgPath, funcDecl)
+				if len(symbols) == 0 {
+					continue
+				}
+				var linkedIn, droppedFrom []string
+				for _, binary := range binaries {
+					if binary.Symbols.HasAny(symbols...) {
+						linkedIn = append(linkedIn, binary.MainPackage)
+					} else {
+						droppedFrom = append(droppedFrom, binary.MainPackage)
+					}
+				}
+				if len(droppedFrom) == 0 || (len(linkedIn) > 0 && !perBinary) {
+					continue
+				}
+				reporting.ReportVanished(VanishedInfo{
+					AnalysisPath: analysisPath,
+					Pkg:          pkg,
+					FuncName:     funcDecl.Name.Name,
+					FuncReceiver: FuncReceiver(funcDecl),
+					Start:        funcDecl.Name,
+					End:          funcDecl.Name,
+					Generated:    isGenerated,
+					FuncReason:   FuncDroppedByLinker,
+					LinkedIn:     linkedIn,
+					DroppedFrom:  droppedFrom,
+				})
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextSymbols(t *testing.T) {
+	output := strings.Join([]string{
+		"  4a3b20 T example.com/module/internal/store.(*Store).Get",
+		"  4a3c40 t example.com/module/internal/store.Value.String",
+		"  52d1a0 R type:struct { Name int32; Typ int32 }",
+		"         U _cgo_init",
+		"  4a3d00 T main.main",
+	}, "\n")
+	symbols := ParseTextSymbols(bufio.NewScanner(strings.NewReader(output)))
+	require.Equal(t, NewSet(
+		"example.com/module/internal/store.(*Store).Get",
+		"example.com/module/internal/store.Value.String",
+		"main.main",
+	), symbols)
+}
+
+// genStoreModule creates main package (using Store through Getter interface) with internal store package
+func genStoreModule(t *testing.T) (string, func()) {
+	dir, dispose, err := MustGenMod(`package main
+
+import "github.com/sivukhin/govanish/PKG/internal/store"
+
+func main() {
+	var g store.Getter = store.New()
+	println(g.Get("a"))
+}`)
+	require.Nil(t, err)
+	src, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(strings.ReplaceAll(string(src), "PKG", filepath.Base(dir))), 0o644))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "internal", "store"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "internal", "store", "store.go"), []byte(`package store
+
+type Getter interface{ Get(key string) string }
+
+type Store struct{ data map[string]string }
+
+func New() *Store { return &Store{data: map[string]string{}} }
+
+func (s *Store) Get(key string) string { return s.data[key] }
+
+// Stale is left from the previous version of Getter interface
+func (s *Store) Stale(key string) string { return "stale " + key }
+
+func (s *Store) unexported() int { return 1 }
+
+type List[T any] struct{ items []T }
+
+func (l *List[T]) Push(v T) { l.items = append(l.items, v) }
+`), 0o644))
+	return dir, dispose
+}
+
+func TestAnalyzeUnusedMethods(t *testing.T) {
+	t.Run("dropped from every binary", func(t *testing.T) {
+		dir, dispose := genStoreModule(t)
+		defer dispose()
+
+		project, err := LoadPackage(dir)
+		require.Nil(t, err)
+		policy := &testPolicy{}
+		require.Nil(t, AnalyzeUnusedMethods(dir, project, BuildConfig{}, false, GeneratedFiles{}, policy))
+		require.Equal(t, []simpleVanishedInfo{
+			{Func: "Stale", StartLine: 12, EndLine: 12, FuncReason: FuncDroppedByLinker},
+			{Func: "Push", StartLine: 18, EndLine: 18, FuncReason: FuncDroppedByLinker},
+		}, policy.Vanished)
+	})
+	t.Run("per binary", func(t *testing.T) {
+		dir, dispose := genStoreModule(t)
+		defer dispose()
+		require.Nil(t, os.MkdirAll(filepath.Join(dir, "cmd", "worker"), 0o755))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "cmd", "worker", "main.go"), []byte(fmt.Sprintf(`package main
+
+import "github.com/sivukhin/govanish/%v/internal/store"
+
+func main() {
+	var list store.List[int]
+	list.Push(1)
+	println(store.New().Stale("a"))
+}`, filepath.Base(dir))), 0o644))
+
+		project, err := LoadPackage(dir)
+		require.Nil(t, err)
+		collect := &CollectReporting{}
+		require.Nil(t, AnalyzeUnusedMethods(dir, project, BuildConfig{}, true, GeneratedFiles{}, collect))
+		attribution := make(map[string][2][]string)
+		for _, info := range collect.Vanished {
+			attribution[info.FuncName] = [2][]string{info.LinkedIn, info.DroppedFrom}
+		}
+		require.Equal(t, map[string][2][]string{
+			"Get":   {{"."}, {"cmd/worker"}},
+			"Stale": {{"cmd/worker"}, {"."}},
+			"Push":  {{"cmd/worker"}, {"."}},
+		}, attribution)
+	})
+}
+
+func TestNoInlineGcflags(t *testing.T) {
+	require.Equal(t, []string{"all=-l"}, NoInlineGcflags(nil))
+	require.Equal(t, []string{"all=-l", "-N -l"}, NoInlineGcflags([]string{"-N"}))
+	require.Equal(
+		t,
+		[]string{"all=-l", "all=-N -l", "std=-l", "example.com/m/...=-race -l"},
+		NoInlineGcflags([]string{"all=-N", "std=", "example.com/m/...=-race"}),
+	)
+}
+
+func TestStripTypeArguments(t *testing.T) {
+	require.Equal(t, "pkg.(*List).Push", StripTypeArguments("pkg.(*List[go.shape.int]).Push"))
+	require.Equal(t, "pkg.Map", StripTypeArguments("pkg.Map[go.shape.int,go.shape.struct { A []int }]"))
+	require.Equal(t, "pkg.Store.Get", StripTypeArguments("pkg.Store.Get"))
+}
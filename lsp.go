@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// minimal subset of the Language Server Protocol: govanish only publishes diagnostics after the file is saved
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspRelatedInformation struct {
+	Location lspLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+type lspDiagnostic struct {
+	Range              lspRange                `json:"range"`
+	Severity           int                     `json:"severity"`
+	Source             string                  `json:"source"`
+	Message            string                  `json:"message"`
+	RelatedInformation []lspRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type lspPublishDiagnostics struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+const (
+	lspMethodNotFound       = -32601
+	lspSeverityWarning      = 2
+	lspTextDocumentSyncNone = 0 // content changes are never sent: analysis needs the files saved on disk
+
+	lspPositionEncodingUtf8  = "utf-8"
+	lspPositionEncodingUtf16 = "utf-16" // default encoding of the protocol
+)
+
+// ReadLspMessage reads single message framed with Content-Length header
+func ReadLspMessage(reader *bufio.Reader) (lspMessage, error) {
+	contentLength := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return lspMessage{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return lspMessage{}, fmt.Errorf("invalid Content-Length header: %q", line)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return lspMessage{}, fmt.Errorf("message without Content-Length header")
+	}
+	content := make([]byte, contentLength)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return lspMessage{}, err
+	}
+	var message lspMessage
+	if err := json.Unmarshal(content, &message); err != nil {
+		return lspMessage{}, fmt.Errorf("unable to decode message: %w", err)
+	}
+	return message, nil
+}
+
+func WriteLspMessage(w io.Writer, message lspMessage) error {
+	message.JSONRPC = "2.0"
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %v\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func PathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func URIToPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return "", fmt.Errorf("unsupported document uri: %v", uri)
+	}
+	return filepath.FromSlash(parsed.Path), nil
+}
+
+// FindModuleRoot returns the closest directory with go.mod file containing dir
+func FindModuleRoot(dir string) (string, error) {
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			return current, nil
+		}
+		if filepath.Dir(current) == current {
+			return "", fmt.Errorf("no go.mod found in '%v' or its parents", dir)
+		}
+	}
+}
+
+type LspServer struct {
+	AnalysisPath string // module root, detected from the workspace root if empty
+	Jobs         int
+	NoCache      bool
+	BuildConfig  func(analysisPath string) (BuildConfig, error)
+	Generated    GeneratedFiles
+
+	out          io.Writer
+	writeLock    sync.Mutex
+	analysisPath string
+	build        BuildConfig
+	cache        *AssemblyCache
+	published    Set    // documents with non-empty diagnostics from the previous analysis
+	encoding     string // position encoding negotiated with the client
+	trigger      chan struct{}
+	done         sync.WaitGroup
+}
+
+// Serve processes messages until exit notification or the end of input
+func (s *LspServer) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	s.published = make(Set)
+	s.trigger = make(chan struct{}, 1)
+	defer s.done.Wait()
+	defer close(s.trigger)
+	reader := bufio.NewReader(in)
+	for {
+		message, err := ReadLspMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch message.Method {
+		case "initialize":
+			err = s.initialize(message)
+		case "initialized", "textDocument/didSave":
+			s.scheduleAnalysis()
+		case "shutdown":
+			err = s.reply(message, nil)
+		case "exit":
+			return nil
+		default:
+			if message.ID != nil && message.Method != "" {
+				err = s.write(lspMessage{ID: message.ID, Error: &lspError{Code: lspMethodNotFound, Message: "method not supported: " + message.Method}})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *LspServer) initialize(message lspMessage) error {
+	var params struct {
+		RootURI      string `json:"rootUri"`
+		RootPath     string `json:"rootPath"`
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(message.Params, &params); err != nil {
+		return fmt.Errorf("invalid initialize params: %w", err)
+	}
+	// columns are already counted in bytes, so utf-8 is preferred when client supports it
+	s.encoding = lspPositionEncodingUtf16
+	if slices.Contains(params.Capabilities.General.PositionEncodings, lspPositionEncodingUtf8) {
+		s.encoding = lspPositionEncodingUtf8
+	}
+	root := params.RootPath
+	if params.RootURI != "" {
+		path, err := URIToPath(params.RootURI)
+		if err != nil {
+			return err
+		}
+		root = path
+	}
+	s.analysisPath = s.AnalysisPath
+	if s.analysisPath == "" {
+		analysisPath, err := FindModuleRoot(root)
+		if err != nil {
+			return err
+		}
+		s.analysisPath = analysisPath
+	}
+	build, err := s.BuildConfig(s.analysisPath)
+	if err != nil {
+		return err
+	}
+	s.build = build
+	if !s.NoCache {
+		s.cache, err = OpenAssemblyCache(build)
+		if err != nil {
+			log.Printf("assembly cache disabled: %v", err)
+		}
+	}
+	s.done.Add(1)
+	go s.analysisLoop()
+	log.Printf("module path: %v", s.analysisPath)
+	return s.reply(message, map[string]any{
+		"capabilities": map[string]any{
+			"positionEncoding": s.encoding,
+			"textDocumentSync": map[string]any{"openClose": true, "change": lspTextDocumentSyncNone, "save": true},
+		},
+		"serverInfo": map[string]any{"name": "govanish"},
+	})
+}
+
+func (s *LspServer) scheduleAnalysis() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+		// analysis is already scheduled and will see all saved changes
+	}
+}
+
+func (s *LspServer) analysisLoop() {
+	defer s.done.Done()
+	for range s.trigger {
+		vanished, err := s.analyze()
+		if err != nil {
+			log.Printf("analysis failed: %v", err)
+			continue
+		}
+		if err := s.publish(vanished); err != nil {
+			log.Printf("unable to publish diagnostics: %v", err)
+		}
+	}
+}
+
+// analyze runs analysis of the whole module: assembly of unchanged packages is loaded from the cache
+func (s *LspServer) analyze() ([]VanishedInfo, error) {
+	project, err := LoadPackages(s.analysisPath, s.build.Patterns(), s.build.Flags...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load project '%v': %w", s.analysisPath, err)
+	}
+	funcRegistry := CreateFuncRegistry(project)
+	projectPkgs := make(map[string]*packages.Package)
+	for _, pkg := range project {
+		projectPkgs[pkg.PkgPath] = pkg
+	}
+	collect := &CollectReporting{}
+	assembly, err := AnalyzeModuleAssemblyParallel(s.analysisPath, s.build, s.Jobs, s.cache, func(importPath string, assembly Assembly) {
+		if pkg, ok := projectPkgs[importPath]; ok {
+			AnalyzePackageAst(s.analysisPath, pkg, assembly.Lines, funcRegistry, s.Generated, Govanish, collect)
+		}
+	})
+	if len(assembly.Lines) == 0 && err != nil {
+		return nil, err
+	}
+	return collect.Vanished, nil
+}
+
+func (s *LspServer) publish(vanished []VanishedInfo) error {
+	diagnostics := make(map[string][]lspDiagnostic)
+	for _, info := range vanished {
+		uri := PathToURI(info.Filename())
+		diagnostics[uri] = append(diagnostics[uri], VanishedDiagnostic(info, s.encoding))
+	}
+	// documents without findings must be cleared explicitly
+	for uri := range s.published {
+		if _, ok := diagnostics[uri]; !ok {
+			diagnostics[uri] = []lspDiagnostic{}
+		}
+	}
+	s.published = make(Set)
+	for uri, documentDiagnostics := range diagnostics {
+		if len(documentDiagnostics) > 0 {
+			s.published[uri] = struct{}{}
+		}
+		params, err := json.Marshal(lspPublishDiagnostics{URI: uri, Diagnostics: documentDiagnostics})
+		if err != nil {
+			return err
+		}
+		if err := s.write(lspMessage{Method: "textDocument/publishDiagnostics", Params: params}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VanishedDiagnostic converts finding to the diagnostic (LSP positions are zero-based, columns are counted in the given encoding)
+func VanishedDiagnostic(info VanishedInfo, encoding string) lspDiagnostic {
+	start, end := info.Region()
+	diagnostic := lspDiagnostic{
+		Range:    newLspRange(start, end, encoding),
+		Severity: lspSeverityWarning,
+		Source:   "govanish",
+		Message:  "seems like " + vanishedMessage(info, "code"),
+	}
+	for _, related := range info.Related {
+		relatedStart, relatedEnd := info.RelatedRange(related)
+		diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, lspRelatedInformation{
+			Location: lspLocation{URI: PathToURI(relatedStart.Filename), Range: newLspRange(relatedStart, relatedEnd, encoding)},
+			Message:  related.Message,
+		})
+	}
+	return diagnostic
+}
+
+func newLspRange(start, end token.Position, encoding string) lspRange {
+	return lspRange{
+		Start: lspPosition{Line: start.Line - 1, Character: lspCharacter(start, encoding)},
+		End:   lspPosition{Line: end.Line - 1, Character: lspCharacter(end, encoding)},
+	}
+}
+
+// lspCharacter converts byte column of the position into the number of utf-16 code units preceding it in the line
+func lspCharacter(position token.Position, encoding string) int {
+	if encoding == lspPositionEncodingUtf8 {
+		return position.Column - 1
+	}
+	content, err := LoadedSource(position.Filename)
+	if err != nil {
+		return position.Column - 1
+	}
+	lines := bytes.Split(content, []byte("\n"))
+	if position.Line < 1 || position.Line > len(lines) {
+		return position.Column - 1
+	}
+	line := lines[position.Line-1]
+	character := 0
+	for _, r := range string(line[:min(position.Column-1, len(line))]) {
+		character += utf16.RuneLen(r)
+	}
+	return character
+}
+
+func (s *LspServer) reply(request lspMessage, result any) error {
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return s.write(lspMessage{ID: request.ID, Result: result})
+}
+
+func (s *LspServer) write(message lspMessage) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return WriteLspMessage(s.out, message)
+}
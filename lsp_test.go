@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLspMessageFraming(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	id := json.RawMessage("1")
+	require.Nil(t, WriteLspMessage(buffer, lspMessage{ID: &id, Method: "initialize", Params: json.RawMessage(`{"rootUri":"file:///module"}`)}))
+	require.Nil(t, WriteLspMessage(buffer, lspMessage{Method: "exit"}))
+
+	reader := bufio.NewReader(buffer)
+	message, err := ReadLspMessage(reader)
+	require.Nil(t, err)
+	require.Equal(t, "initialize", message.Method)
+	require.Equal(t, json.RawMessage("1"), *message.ID)
+	message, err = ReadLspMessage(reader)
+	require.Nil(t, err)
+	require.Equal(t, "exit", message.Method)
+	require.Nil(t, message.ID)
+	_, err = ReadLspMessage(reader)
+	require.Equal(t, io.EOF, err)
+
+	_, err = ReadLspMessage(bufio.NewReader(bytes.NewBufferString("Content-Type: text\r\n\r\n{}")))
+	require.NotNil(t, err)
+}
+
+func TestLspServer(t *testing.T) {
+	dir, dispose, err := MustGenMod(`package main
+
+func NoErrCheck(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+	_ = w.Write(2)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {}`)
+	require.Nil(t, err)
+	defer dispose()
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	server := &LspServer{
+		AnalysisPath: dir,
+		Jobs:         1,
+		NoCache:      true,
+		BuildConfig:  func(string) (BuildConfig, error) { return BuildConfig{}, nil },
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(serverIn, serverOut) }()
+	responses := bufio.NewReader(clientIn)
+
+	id := json.RawMessage("1")
+	require.Nil(t, WriteLspMessage(clientOut, lspMessage{ID: &id, Method: "initialize", Params: json.RawMessage(`{"rootUri":"` + PathToURI(dir) + `"}`)}))
+	response, err := ReadLspMessage(responses)
+	require.Nil(t, err)
+	require.Equal(t, json.RawMessage("1"), *response.ID)
+	require.Nil(t, response.Error)
+	capabilities := response.Result.(map[string]any)["capabilities"].(map[string]any)
+	require.Equal(t, "utf-16", capabilities["positionEncoding"])
+
+	require.Nil(t, WriteLspMessage(clientOut, lspMessage{Method: "initialized", Params: json.RawMessage("{}")}))
+	notification, err := ReadLspMessage(responses)
+	require.Nil(t, err)
+	require.Equal(t, "textDocument/publishDiagnostics", notification.Method)
+	var diagnostics lspPublishDiagnostics
+	require.Nil(t, json.Unmarshal(notification.Params, &diagnostics))
+	require.Equal(t, PathToURI(filepath.Join(dir, "main.go")), diagnostics.URI)
+	require.Len(t, diagnostics.Diagnostics, 1)
+	require.Equal(t, lspRange{Start: lspPosition{Line: 9, Character: 2}, End: lspPosition{Line: 9, Character: 12}}, diagnostics.Diagnostics[0].Range)
+	require.Equal(t, []lspRelatedInformation{
+		{
+			Location: lspLocation{URI: diagnostics.URI, Range: lspRange{Start: lspPosition{Line: 7, Character: 1}, End: lspPosition{Line: 7, Character: 15}}},
+			Message:  "error assigned here is never checked",
+		},
+		{
+			Location: lspLocation{URI: diagnostics.URI, Range: lspRange{Start: lspPosition{Line: 8, Character: 4}, End: lspPosition{Line: 8, Character: 14}}},
+			Message:  "condition checks err instead of the error assigned before it",
+		},
+	}, diagnostics.Diagnostics[0].RelatedInformation)
+
+	id = json.RawMessage("2")
+	require.Nil(t, WriteLspMessage(clientOut, lspMessage{ID: &id, Method: "shutdown"}))
+	response, err = ReadLspMessage(responses)
+	require.Nil(t, err)
+	require.Equal(t, json.RawMessage("2"), *response.ID)
+	require.Nil(t, WriteLspMessage(clientOut, lspMessage{Method: "exit"}))
+	require.Nil(t, <-served)
+}
+
+func TestLspCharacter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.go")
+	// "ы" takes 2 bytes and 1 utf-16 code unit, "𝔸" takes 4 bytes and 2 utf-16 code units
+	require.Nil(t, os.WriteFile(filename, []byte("package main\n\nvar s = \"ы𝔸\"; var x = 1\n"), 0o644))
+	position := token.Position{Filename: filename, Line: 3, Column: len("var s = \"ы𝔸\"; ") + 1}
+	t.Run("utf-16", func(t *testing.T) {
+		require.Equal(t, len("var s = \"__\"; ")+1, lspCharacter(position, lspPositionEncodingUtf16))
+	})
+	t.Run("utf-8", func(t *testing.T) {
+		require.Equal(t, len("var s = \"ы𝔸\"; "), lspCharacter(position, lspPositionEncodingUtf8))
+	})
+	t.Run("ascii prefix", func(t *testing.T) {
+		require.Equal(t, 4, lspCharacter(token.Position{Filename: filename, Line: 3, Column: 5}, lspPositionEncodingUtf16))
+	})
+}
@@ -3,61 +3,385 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
 )
 
-func main() {
-	modulePath := flag.String("path", "", "path to the module root (with go.mod file)")
-	reportFormat := flag.String("format", "log", "reporting type (github | log)")
-	flag.Parse()
+type stringsFlag []string
+
+func (s *stringsFlag) String() string     { return strings.Join(*s, ",") }
+func (s *stringsFlag) Set(v string) error { *s = append(*s, v); return nil }
+
+// registerBuildFlags defines go build flags forwarded to the compilation of the analyzed module
+func registerBuildFlags(flags *flag.FlagSet) func(analysisPath string) (BuildConfig, error) {
+	tags := flags.String("tags", "", "comma-separated list of build tags forwarded to go build")
+	ldflags := flags.String("ldflags", "", "-ldflags value forwarded to go build")
+	race := flags.Bool("race", false, "build with data race detection enabled")
+	cover := flags.Bool("cover", false, "build with code coverage instrumentation enabled")
+	pgo := flags.String("pgo", "", "-pgo value forwarded to go build (auto | off | path to profile)")
+	mod := flags.String("mod", "", "-mod value forwarded to go build (readonly | vendor | mod)")
+	var gcflags stringsFlag
+	flags.Var(&gcflags, "gcflags", "[pattern=]flags forwarded to go build, can be repeated (-S is added automatically)")
+	includeReplaced := flags.Bool("include-replaced", false, "analyze dependencies replaced with local directories in go.mod together with the module")
+	return func(analysisPath string) (BuildConfig, error) {
+		var config BuildConfig
+		if *tags != "" {
+			config.Flags = append(config.Flags, "-tags="+*tags)
+		}
+		if *ldflags != "" {
+			config.Flags = append(config.Flags, "-ldflags="+*ldflags)
+		}
+		if *race {
+			config.Flags = append(config.Flags, "-race")
+		}
+		if *cover {
+			config.Flags = append(config.Flags, "-cover")
+		}
+		if *pgo == "auto" || *pgo == "off" {
+			config.Flags = append(config.Flags, "-pgo="+*pgo)
+		} else if *pgo != "" {
+			// go build runs in the module directory, so profile path must not depend on the working directory
+			profile, err := filepath.Abs(*pgo)
+			if err != nil {
+				profile = *pgo
+			}
+			config.Flags = append(config.Flags, "-pgo="+profile)
+		}
+		if *mod != "" {
+			config.Flags = append(config.Flags, "-mod="+*mod)
+		}
+		config.Gcflags = gcflags
+		if *includeReplaced {
+			replaced, err := ListLocalReplacements(analysisPath, config)
+			if err != nil {
+				return BuildConfig{}, err
+			}
+			config.Replaced = replaced
+		}
+		return config, nil
+	}
+}
 
+// registerGeneratedFlags defines which generated files are analyzed together with the hand-written code
+func registerGeneratedFlags(flags *flag.FlagSet) func() GeneratedFiles {
+	includeGenerated := flags.Bool("include-generated", false, "analyze generated files (marked with \"Code generated ... DO NOT EDIT.\" comment)")
+	var globs stringsFlag
+	flags.Var(&globs, "include-generated-glob", "pattern of the generated files to analyze relative to the module root (or file name pattern), can be repeated")
+	return func() GeneratedFiles {
+		return GeneratedFiles{All: *includeGenerated, Globs: globs}
+	}
+}
+
+// documentFormats write the whole document on flush, so they can't be appended after every -watch round
+var documentFormats = NewSet("html", "checkstyle", "junit", "gitlab", "json", "sarif")
+
+// createReporting creates reporting of the given format: all formats except log print into the out file
+// incremental reportings get only new findings on every flush (-watch mode)
+func createReporting(reportFormat string, out *os.File, templatePositions bool, incremental bool) (Reporting, error) {
 	var reporting Reporting
-	if *reportFormat == "github" {
-		reporting = GitHubReporting{}
-	} else if *reportFormat == "log" {
+	if reportFormat == "github" {
+		reporting = GitHubReporting{Out: out}
+	} else if reportFormat == "log" {
 		reporting = LogReporting{}
+	} else if reportFormat == "pretty" {
+		reporting = &PrettyReporting{Out: out, Color: IsTerminal(out), Context: 2, Incremental: incremental}
+	} else if reportFormat == "html" {
+		reporting = &HtmlReporting{Out: out}
+	} else if reportFormat == "checkstyle" {
+		reporting = &CheckstyleReporting{Out: out}
+	} else if reportFormat == "junit" {
+		reporting = &JUnitReporting{Out: out}
+	} else if reportFormat == "gitlab" {
+		reporting = &GitLabReporting{Out: out}
+	} else if reportFormat == "json" {
+		reporting = &JsonReporting{Out: out}
+	} else if reportFormat == "sarif" {
+		reporting = &SarifReporting{Out: out}
 	} else {
-		fmt.Printf("invalid -format value: %v\n", *reportFormat)
-		flag.Usage()
+		return nil, fmt.Errorf("invalid -format value: %v", reportFormat)
+	}
+	if templatePositions {
+		reporting = TemplatePositionsReporting{Reporting: reporting}
+	}
+	return reporting, nil
+}
+
+func openReportOutput(path string) (*os.File, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
+
+func mustFlushReport(reporting Reporting) {
+	if err := FlushReport(reporting); err != nil {
+		panic(fmt.Errorf("failed to write report: %w", err))
+	}
+}
+
+func resolveAnalysisPath(modulePath string) (string, error) {
+	if modulePath == "" {
+		analysisPath, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("unable to get working directory: %w", err)
+		}
+		return analysisPath, nil
+	}
+	analysisPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to expand path '%v' to absolute: %w", modulePath, err)
+	}
+	return analysisPath, nil
+}
+
+func compare(args []string) {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	modulePath := flags.String("path", "", "path to the module root (with go.mod file)")
+	reportFormat := flags.String("format", "log", "reporting type for newly vanished code (github | log | pretty | html | checkstyle | junit | gitlab | json | sarif)")
+	outputPath := flags.String("o", "", "file to write the report into (stdout by default)")
+	baseRevision := flags.String("base", "", "git revision to compare the working tree with")
+	pgoDiff := flags.Bool("pgo-diff", false, "compare builds with and without profile-guided optimization (default.pgo of main packages or -pgo profile)")
+	templatePositions := flags.Bool("template-positions", false, "report findings in generated files against the template source from //line directives")
+	summaryPath := flags.String("summary", "", "file to write markdown summary of the -base comparison into (e.g. $GITHUB_STEP_SUMMARY)")
+	var toolchains stringsFlag
+	flags.Var(&toolchains, "toolchain", "locally installed toolchain name (go1.23) or GOROOT path; specify twice: base and target")
+	buildConfig := registerBuildFlags(flags)
+	generatedFiles := registerGeneratedFlags(flags)
+	_ = flags.Parse(args)
+
+	out, err := openReportOutput(*outputPath)
+	if err != nil {
+		panic(fmt.Errorf("unable to create report file '%v': %w", *outputPath, err))
+	}
+	defer out.Close()
+	reporting, err := createReporting(*reportFormat, out, *templatePositions, false)
+	if err != nil {
+		fmt.Println(err)
+		flags.Usage()
+		os.Exit(1)
+	}
+	analysisPath, err := resolveAnalysisPath(*modulePath)
+	if err != nil {
+		fmt.Println(err)
+		flags.Usage()
+		os.Exit(1)
+	}
+	modes := 0
+	for _, enabled := range []bool{*baseRevision != "", len(toolchains) > 0, *pgoDiff} {
+		if enabled {
+			modes++
+		}
+	}
+	if modes != 1 {
+		fmt.Println("exactly one of -base, -toolchain or -pgo-diff must be provided")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	build, err := buildConfig(analysisPath)
+	if err != nil {
+		fmt.Println(err)
+		flags.Usage()
 		os.Exit(1)
 	}
 
-	var analysisPath string
-	var err error
-	if *modulePath == "" {
-		analysisPath, err = os.Getwd()
+	log.Printf("module path: %v", analysisPath)
+	if *pgoDiff {
+		err = ComparePgo(analysisPath, build, generatedFiles(), reporting)
 		if err != nil {
-			fmt.Printf("unable to get working directory: %v\n", err)
-			flag.Usage()
-			os.Exit(1)
+			panic(fmt.Errorf("failed to compare PGO build: %w", err))
 		}
-	} else {
-		analysisPath, err = filepath.Abs(*modulePath)
+		mustFlushReport(reporting)
+		return
+	}
+	if *baseRevision != "" {
+		var summary io.Writer
+		if *summaryPath != "" {
+			f, err := os.OpenFile(*summaryPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				panic(fmt.Errorf("unable to open summary file '%v': %w", *summaryPath, err))
+			}
+			defer f.Close()
+			summary = f
+		}
+		err = CompareRevisions(analysisPath, build, generatedFiles(), *baseRevision, reporting, summary)
 		if err != nil {
-			fmt.Printf("unable to expand path '%v' to absolute: %v\n", *modulePath, err)
+			panic(fmt.Errorf("failed to compare revisions: %w", err))
+		}
+		mustFlushReport(reporting)
+		return
+	}
+	if len(toolchains) != 2 {
+		fmt.Printf("exactly two -toolchain values expected, got %v\n", len(toolchains))
+		flags.Usage()
+		os.Exit(1)
+	}
+	err = CompareToolchains(analysisPath, build, generatedFiles(), toolchains, reporting)
+	if err != nil {
+		panic(fmt.Errorf("failed to compare toolchains: %w", err))
+	}
+	mustFlushReport(reporting)
+}
+
+func lsp(args []string) {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	modulePath := flags.String("path", "", "path to the module root (with go.mod file), detected from the workspace root by default")
+	jobs := flags.Int("j", runtime.NumCPU(), "maximum number of packages compiled concurrently")
+	noCache := flags.Bool("no-cache", false, "compile every package on every save even if its assembly is cached")
+	buildConfig := registerBuildFlags(flags)
+	generatedFiles := registerGeneratedFlags(flags)
+	_ = flags.Parse(args)
+
+	server := &LspServer{Jobs: *jobs, NoCache: *noCache, BuildConfig: buildConfig, Generated: generatedFiles()}
+	if *modulePath != "" {
+		analysisPath, err := resolveAnalysisPath(*modulePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		server.AnalysisPath = analysisPath
+	}
+	// stdout is reserved for the protocol messages - logs are written to stderr
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		log.Printf("language server stopped: %v", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		compare(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		lsp(os.Args[2:])
+		return
+	}
+
+	modulePath := flag.String("path", "", "path to the module root (with go.mod file)")
+	reportFormat := flag.String("format", "log", "reporting type (github | log | pretty | html | checkstyle | junit | gitlab | json | sarif)")
+	outputPath := flag.String("o", "", "file to write the report into (stdout by default)")
+	jobs := flag.Int("j", runtime.NumCPU(), "maximum number of packages compiled concurrently")
+	templatePositions := flag.Bool("template-positions", false, "report findings in generated files against the template source from //line directives")
+	funcs := flag.Bool("funcs", false, "also report functions which were never compiled or exist only inlined into the callers")
+	unusedMethods := flag.Bool("unused-methods", false, "also report exported methods of internal packages which linker drops from every main binary of the module")
+	perBinary := flag.Bool("per-binary", false, "build every main package separately and also report methods dropped by linker only from some of the binaries (other findings are not attributed to binaries)")
+	noCache := flag.Bool("no-cache", false, "compile every package even if its assembly is cached from the previous run")
+	fix := flag.Bool("fix", false, "apply suggested fixes of the recognized bugs (like duplicate error check) to the module sources")
+	watch := flag.Bool("watch", false, "keep watching module files and analyze affected packages again after every change")
+	watchInterval := flag.Duration("watch-interval", time.Second, "how often module files are polled for changes in -watch mode")
+	buildConfig := registerBuildFlags(flag.CommandLine)
+	generatedFiles := registerGeneratedFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *watch {
+		var conflicts []string
+		for name, set := range map[string]bool{"-fix": *fix, "-funcs": *funcs, "-unused-methods": *unusedMethods, "-per-binary": *perBinary} {
+			if set {
+				conflicts = append(conflicts, name)
+			}
+		}
+		if documentFormats.Has(*reportFormat) {
+			conflicts = append(conflicts, "-format "+*reportFormat)
+		}
+		if len(conflicts) > 0 {
+			slices.Sort(conflicts)
+			fmt.Printf("%v can't be combined with -watch\n", strings.Join(conflicts, ", "))
 			flag.Usage()
 			os.Exit(1)
 		}
 	}
+	out, err := openReportOutput(*outputPath)
+	if err != nil {
+		panic(fmt.Errorf("unable to create report file '%v': %w", *outputPath, err))
+	}
+	defer out.Close()
+	reporting, err := createReporting(*reportFormat, out, *templatePositions, *watch)
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	analysisPath, err := resolveAnalysisPath(*modulePath)
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	build, err := buildConfig(analysisPath)
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(1)
+	}
 
 	log.Printf("module path: %v", analysisPath)
-	assemblyLines, err := AnalyzeModuleAssembly(analysisPath)
-	if len(assemblyLines) == 0 && err != nil {
-		panic(fmt.Errorf("failed to analyze module assembly: %w", err))
+	generated := generatedFiles()
+	var cache *AssemblyCache
+	if !*noCache {
+		cache, err = OpenAssemblyCache(build)
+		if err != nil {
+			log.Printf("assembly cache disabled: %v", err)
+		}
 	}
-	if err != nil {
-		log.Printf("module analysis finished with non-critical error: %v", err)
+	if *watch {
+		watcher := &Watcher{AnalysisPath: analysisPath, Build: build, Jobs: *jobs, Cache: cache, Generated: generated, Reporting: reporting}
+		if err := watcher.Watch(*watchInterval); err != nil {
+			panic(fmt.Errorf("failed to watch module: %w", err))
+		}
+		return
 	}
-	project, err := LoadPackage(analysisPath)
+	var fixes *FixReporting
+	if *fix {
+		fixes = &FixReporting{Reporting: reporting}
+		reporting = fixes
+	}
+	project, err := LoadPackages(analysisPath, build.Patterns(), build.Flags...)
 	if err != nil {
 		panic(fmt.Errorf("unable to load project '%v': %w", analysisPath, err))
 	}
 	funcRegistry := CreateFuncRegistry(project)
+	projectPkgs := make(map[string]*packages.Package)
+	for _, pkg := range project {
+		projectPkgs[pkg.PkgPath] = pkg
+	}
 
-	err = AnalyzeModuleAst(analysisPath, project, assemblyLines, funcRegistry, Govanish, reporting)
+	// analyze package AST as soon as assembly of the package and of its dependants is ready instead of waiting for the whole module
+	assembly, err := AnalyzeModuleAssemblyParallel(analysisPath, build, *jobs, cache, func(importPath string, assembly Assembly) {
+		if pkg, ok := projectPkgs[importPath]; ok {
+			AnalyzePackageAst(analysisPath, pkg, assembly.Lines, funcRegistry, generated, Govanish, reporting)
+		}
+	})
+	if len(assembly.Lines) == 0 && err != nil {
+		panic(fmt.Errorf("failed to analyze module assembly: %w", err))
+	}
 	if err != nil {
-		panic(fmt.Errorf("failed to analyze module AST: %w", err))
+		log.Printf("module analysis finished with non-critical error: %v", err)
+	}
+	ReportAssembly(reporting, analysisPath, project, assembly)
+	if *funcs {
+		for _, pkg := range project {
+			AnalyzePackageFuncs(analysisPath, pkg, assembly, generated, reporting)
+		}
+	}
+	if *unusedMethods || *perBinary {
+		if err := AnalyzeUnusedMethods(analysisPath, project, build, *perBinary, generated, reporting); err != nil {
+			panic(fmt.Errorf("failed to analyze unused methods: %w", err))
+		}
+	}
+	mustFlushReport(reporting)
+	if fixes != nil {
+		if err := fixes.Apply(); err != nil {
+			panic(fmt.Errorf("failed to apply suggested fixes: %w", err))
+		}
 	}
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const defaultPgoProfile = "default.pgo"
+
+// DetectPgoProfiles finds default.pgo profiles of the main packages which go build uses with -pgo=auto (default mode)
+func DetectPgoProfiles(pkgs []ModulePackage) []string {
+	var profiles []string
+	for _, pkg := range pkgs {
+		if pkg.Name != "main" {
+			continue
+		}
+		profile := filepath.Join(pkg.Dir, defaultPgoProfile)
+		if stat, err := os.Stat(profile); err == nil && !stat.IsDir() {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+// PgoMode returns value of the -pgo build flag (auto if it is not set explicitly)
+func (c BuildConfig) PgoMode() string {
+	mode := "auto"
+	for _, flag := range c.Flags {
+		if value, ok := strings.CutPrefix(flag, "-pgo="); ok {
+			mode = value
+		}
+	}
+	return mode
+}
+
+func (c BuildConfig) WithPgo(mode string) BuildConfig {
+	c.Flags = slices.DeleteFunc(slices.Clone(c.Flags), func(flag string) bool { return strings.HasPrefix(flag, "-pgo=") })
+	c.Flags = append(c.Flags, "-pgo="+mode)
+	return c
+}
+
+// PgoVariants returns build configurations which should be used to compile the package
+// with -pgo=auto go build applies profile of the main package to all its dependencies, but when library package is compiled alone
+// no profile is used - so we compile library packages with every detected profile and without profile at all
+func PgoVariants(pkg ModulePackage, config BuildConfig, profiles []string) []BuildConfig {
+	if pkg.Name == "main" || config.PgoMode() != "auto" || len(profiles) == 0 {
+		return []BuildConfig{config}
+	}
+	variants := []BuildConfig{config}
+	for _, profile := range profiles {
+		variants = append(variants, config.WithPgo(profile))
+	}
+	return variants
+}
+
+// ComparePgo reports code which vanishes only when module is compiled with profile-guided optimizations
+func ComparePgo(analysisPath string, build BuildConfig, generated GeneratedFiles, reporting Reporting) error {
+	if mode := build.PgoMode(); mode == "off" {
+		return fmt.Errorf("PGO comparison is impossible with -pgo=off")
+	} else if mode == "auto" {
+		pkgs, err := ListModulePackages(analysisPath, build)
+		if err != nil {
+			return err
+		}
+		profiles := DetectPgoProfiles(pkgs)
+		if len(profiles) == 0 {
+			return fmt.Errorf("no %v profiles found in main packages: provide profile with -pgo flag", defaultPgoProfile)
+		}
+		log.Printf("detected PGO profiles: %v", profiles)
+	}
+	project, err := LoadPackages(analysisPath, build.Patterns(), build.Flags...)
+	if err != nil {
+		return fmt.Errorf("unable to load project '%v': %w", analysisPath, err)
+	}
+	funcRegistry := CreateFuncRegistry(project)
+
+	log.Printf("analyzing module without PGO")
+	base, err := AnalyzeModuleVanished(analysisPath, project, funcRegistry, build.WithPgo("off"), generated)
+	if err != nil {
+		return fmt.Errorf("without PGO: %w", err)
+	}
+	log.Printf("analyzing module with -pgo=%v", build.PgoMode())
+	head, err := AnalyzeModuleVanished(analysisPath, project, funcRegistry, build, generated)
+	if err != nil {
+		return fmt.Errorf("with PGO: %w", err)
+	}
+
+	added, removed, _ := DiffVanished(base, head, VanishedKey)
+	log.Printf("compared PGO build against regular one: %v vanished only with PGO, %v vanished only without PGO", len(added), len(removed))
+	for _, info := range removed {
+		log.Printf(
+			"code vanished only without PGO: func=[%v], file=[%v], lines=[%v-%v]",
+			info.FuncName,
+			info.Filename(),
+			info.StartLine(),
+			info.EndLine(),
+		)
+	}
+	for _, info := range added {
+		reporting.ReportVanished(info)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgoVariants(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, defaultPgoProfile), []byte("profile"), 0o644))
+	pkgs := []ModulePackage{{ImportPath: "m/cmd", Name: "main", Dir: dir}, {ImportPath: "m/lib", Name: "lib", Dir: dir}}
+	profiles := DetectPgoProfiles(pkgs)
+	require.Equal(t, []string{filepath.Join(dir, defaultPgoProfile)}, profiles)
+
+	t.Run("main package uses its own profile", func(t *testing.T) {
+		require.Equal(t, []BuildConfig{{}}, PgoVariants(pkgs[0], BuildConfig{}, profiles))
+	})
+	t.Run("library compiled with every profile", func(t *testing.T) {
+		require.Equal(
+			t,
+			[]BuildConfig{{}, {Flags: []string{"-pgo=" + profiles[0]}}},
+			PgoVariants(pkgs[1], BuildConfig{}, profiles),
+		)
+	})
+	t.Run("explicit pgo mode", func(t *testing.T) {
+		config := BuildConfig{Flags: []string{"-tags=x", "-pgo=off"}}
+		require.Equal(t, "off", config.PgoMode())
+		require.Equal(t, []BuildConfig{config}, PgoVariants(pkgs[1], config, profiles))
+		require.Equal(t, BuildConfig{Flags: []string{"-tags=x", "-pgo=auto"}}, config.WithPgo("auto"))
+	})
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -10,10 +15,37 @@ type VanishedInfo struct {
 	AnalysisPath string
 	Pkg          *packages.Package
 	FuncName     string
+	FuncReceiver string // receiver type of the method (like *List[T]), empty for functions
 	Start        ast.Node
 	End          ast.Node
+	// whole function has no own symbol in the compiler output (Start and End point to the function name)
+	FuncReason FuncVanishReason
+	// main packages which binaries keep or drop the function (linker-level findings)
+	LinkedIn    []string
+	DroppedFrom []string
+	// finding is located in the generated file included with GeneratedFiles
+	Generated bool
+	// positions explaining the finding (e.g. assignment which vanished check was meant to guard)
+	Related []RelatedPosition
+	// mechanical edits which most likely fix the bug behind the finding
+	SuggestedFixes []SuggestedFix
+	// report positions from //line directives (original template source) instead of the positions in generated file
+	TemplatePositions bool
 }
 
+type RelatedPosition struct {
+	Node    ast.Node
+	Message string
+}
+
+type FuncVanishReason int
+
+const (
+	FuncCompiled        FuncVanishReason = iota
+	FuncNeverCompiled                    // no symbol and no instructions: e.g. generic function which is never instantiated
+	FuncDroppedByLinker                  // exported method is unreachable from every main package of the module
+)
+
 type AnalysisPolicy interface {
 	ShouldSkip(ctx GovanishContext, node ast.Node) bool
 	IsControlFlowPivot(node ast.Node) bool
@@ -80,11 +112,79 @@ func (g GovanishAnalysisPolicy) CheckComplexity(ctx GovanishContext, node ast.No
 	return complexFlow || operations >= 2
 }
 
-func (i VanishedInfo) Filename() string { return i.Pkg.Fset.Position(i.Start.Pos()).Filename }
-func (i VanishedInfo) StartLine() int   { return i.Pkg.Fset.Position(i.Start.Pos()).Line }
-func (i VanishedInfo) EndLine() int     { return i.Pkg.Fset.Position(i.End.Pos()).Line }
-func (i VanishedInfo) StartLineOffsets() (start, end int) {
-	startPos := i.Pkg.Fset.Position(i.Start.Pos())
-	endPos := i.Pkg.Fset.Position(i.Start.End())
-	return startPos.Offset, endPos.Offset
+func (i VanishedInfo) position(pos token.Pos) token.Position {
+	if i.TemplatePositions {
+		return i.Pkg.Fset.Position(pos)
+	}
+	return SourcePosition(i.Pkg, pos)
+}
+func (i VanishedInfo) RelatedRange(related RelatedPosition) (start, end token.Position) {
+	return i.position(related.Node.Pos()), i.position(related.Node.End())
+}
+
+// QualifiedFuncName distinguishes methods with the same name: (*A).Close
+func (i VanishedInfo) QualifiedFuncName() string {
+	if i.FuncReceiver == "" {
+		return i.FuncName
+	}
+	return fmt.Sprintf("(%v).%v", i.FuncReceiver, i.FuncName)
+}
+
+// FuncReceiver returns receiver type of the method declaration or empty string for functions
+func FuncReceiver(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return ""
+	}
+	return types.ExprString(funcDecl.Recv.List[0].Type)
+}
+func (i VanishedInfo) Filename() string { return i.position(i.Start.Pos()).Filename }
+func (i VanishedInfo) StartLine() int   { return i.position(i.Start.Pos()).Line }
+func (i VanishedInfo) EndLine() int     { return i.position(i.End.End()).Line }
+
+// Region returns exact positions of the first and after the last character of the vanished code
+func (i VanishedInfo) Region() (start, end token.Position) {
+	return i.position(i.Start.Pos()), i.position(i.End.End())
+}
+
+// SourceFilename is the file which was actually parsed: it differs from Filename for cgo and files with //line directives
+// offsets of the nodes always refer to the SourceFilename content
+func (i VanishedInfo) SourceFilename() string {
+	return i.Pkg.Fset.PositionFor(i.Start.Pos(), false).Filename
+}
+
+// SourceRegion returns positions of the vanished code in the SourceFilename content
+func (i VanishedInfo) SourceRegion() (start, end token.Position) {
+	return i.Pkg.Fset.PositionFor(i.Start.Pos(), false), i.Pkg.Fset.PositionFor(i.End.End(), false)
+}
+func (i VanishedInfo) sourceOffsets() (content []byte, start, end int, ok bool) {
+	content, err := LoadedSource(i.SourceFilename())
+	sourceStart, sourceEnd := i.SourceRegion()
+	start, end = sourceStart.Offset, sourceEnd.Offset
+	return content, start, end, err == nil && start <= end && end <= len(content)
+}
+func (i VanishedInfo) Snippet() string {
+	content, start, end, ok := i.sourceOffsets()
+	if !ok {
+		return ""
+	}
+	return string(content[start:end])
+}
+
+// SourceLines returns full source lines containing the vanished code
+func (i VanishedInfo) SourceLines() []string {
+	content, start, end, ok := i.sourceOffsets()
+	if !ok {
+		return nil
+	}
+	start = bytes.LastIndexByte(content[:start], '\n') + 1
+	if newline := bytes.IndexByte(content[end:], '\n'); newline >= 0 {
+		end += newline
+	} else {
+		end = len(content)
+	}
+	lines := strings.Split(string(content[start:end]), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// IsTerminal reports whether file is an interactive terminal (colors are disabled with NO_COLOR environment variable)
+func IsTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// PrettyReporting collects findings and prints them grouped by file and function with code frames on Flush
+type PrettyReporting struct {
+	Out     io.Writer // os.Stdout if nil
+	Color   bool
+	Context int // number of lines printed around vanished code
+	// every flush gets only findings which are new since the previous one (-watch mode),
+	// so flush without findings prints nothing instead of reporting clean module
+	Incremental bool
+
+	findings []VanishedInfo
+}
+
+func (r *PrettyReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func (r *PrettyReporting) style(text string, styles ...string) string {
+	if !r.Color || len(styles) == 0 {
+		return text
+	}
+	return strings.Join(styles, "") + text + ansiReset
+}
+
+func (r *PrettyReporting) relativePath(info VanishedInfo, filename string) string {
+	if relativePath, err := filepath.Rel(info.AnalysisPath, filename); err == nil {
+		return relativePath
+	}
+	return filename
+}
+
+// Flush prints collected findings together with summary table and forgets them
+func (r *PrettyReporting) Flush() error {
+	out := r.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	findings := r.findings
+	r.findings = nil
+	slices.SortStableFunc(findings, func(a, b VanishedInfo) int {
+		if c := strings.Compare(a.Filename(), b.Filename()); c != 0 {
+			return c
+		}
+		return a.StartLine() - b.StartLine()
+	})
+
+	var report strings.Builder
+	type fileSummary struct {
+		Path      string
+		Functions []string
+		Findings  int
+	}
+	var summary []*fileSummary
+	for _, info := range findings {
+		path := r.relativePath(info, info.Filename())
+		if len(summary) == 0 || summary[len(summary)-1].Path != path {
+			summary = append(summary, &fileSummary{Path: path})
+			_, _ = fmt.Fprintf(&report, "%v\n", r.style(path, ansiBold, ansiCyan))
+		}
+		file := summary[len(summary)-1]
+		file.Findings++
+		if !slices.Contains(file.Functions, info.FuncName) {
+			file.Functions = append(file.Functions, info.FuncName)
+			_, _ = fmt.Fprintf(&report, "  %v\n", r.style("func "+info.FuncName, ansiBold))
+		}
+		r.writeFinding(&report, info)
+	}
+
+	if len(findings) == 0 {
+		if !r.Incremental {
+			report.WriteString("no vanished code found\n")
+		}
+	} else {
+		totalLabel := "total"
+		if r.Incremental {
+			totalLabel = "total new"
+		}
+		pathWidth := max(len("file"), len(totalLabel))
+		for _, file := range summary {
+			pathWidth = max(pathWidth, len(file.Path))
+		}
+		total, functions := 0, 0
+		_, _ = fmt.Fprintf(&report, "%v\n", r.style(fmt.Sprintf("%-*v  %9v  %8v", pathWidth, "file", "functions", "findings"), ansiBold))
+		for _, file := range summary {
+			_, _ = fmt.Fprintf(&report, "%-*v  %9v  %8v\n", pathWidth, file.Path, len(file.Functions), file.Findings)
+			total += file.Findings
+			functions += len(file.Functions)
+		}
+		_, _ = fmt.Fprintf(&report, "%v\n", r.style(fmt.Sprintf("%-*v  %9v  %8v", pathWidth, totalLabel, functions, total), ansiBold))
+	}
+	_, err := io.WriteString(out, report.String())
+	return err
+}
+
+// writeFinding prints message with code frame: vanished lines are highlighted and marked in the gutter
+func (r *PrettyReporting) writeFinding(report *strings.Builder, info VanishedInfo) {
+	start, _ := info.Region()
+	_, _ = fmt.Fprintf(report, "    %v %v %v\n", r.style("warning:", ansiBold, ansiYellow), vanishedMessage(info, "code"), r.style(fmt.Sprintf("(%v:%v:%v)", r.relativePath(info, start.Filename), start.Line, start.Column), ansiDim))
+
+	content, err := LoadedSource(info.SourceFilename())
+	if err == nil {
+		lines := strings.Split(string(content), "\n")
+		// frame is taken from the parsed file, but numbered with the reported lines (they differ for //line directives)
+		sourceRegionStart, sourceRegionEnd := info.SourceRegion()
+		sourceStart, sourceEnd := sourceRegionStart.Line, sourceRegionEnd.Line
+		shift := start.Line - sourceStart
+		from, to := max(1, sourceStart-r.Context), min(len(lines), sourceEnd+r.Context)
+		width := len(fmt.Sprint(to + shift))
+		for line := from; line <= to; line++ {
+			text := strings.TrimSuffix(lines[line-1], "\r")
+			if sourceStart <= line && line <= sourceEnd {
+				_, _ = fmt.Fprintf(report, "    %v %v\n", r.style(fmt.Sprintf("> %*d |", width, line+shift), ansiBold, ansiRed), r.style(text, ansiRed))
+			} else {
+				_, _ = fmt.Fprintf(report, "    %v %v\n", r.style(fmt.Sprintf("  %*d |", width, line+shift), ansiDim), text)
+			}
+		}
+	}
+	for _, related := range info.Related {
+		relatedStart, _ := info.RelatedRange(related)
+		_, _ = fmt.Fprintf(report, "    %v %v:%v: %v\n", r.style("related:", ansiBold), r.relativePath(info, relatedStart.Filename), relatedStart.Line, related.Message)
+	}
+	report.WriteString("\n")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyReporting(t *testing.T) {
+	dir, dispose := exampleModule(t, "forgotten_errcheck_bug.go")
+	defer dispose()
+
+	out := bytes.NewBuffer(nil)
+	reporting := &PrettyReporting{Out: out, Context: 1}
+	analyzeModule(t, dir, reporting)
+	require.Empty(t, out.String(), "findings must be printed only on flush")
+	require.Nil(t, FlushReport(reporting))
+	require.Equal(t, strings.Join([]string{
+		"main.go",
+		"  func NoErrCheck",
+		"    warning: code vanished from compiled binary (main.go:11:3)",
+		"      10 | \t\t// this line removed by compiler because err were already checked on line 6 and didn't changed since that",
+		"    > 11 | \t\tpanic(err)",
+		"      12 | \t}",
+		"    related: main.go:8: error assigned here is never checked",
+		"    related: main.go:9: condition checks err instead of the error assigned before it",
+		"",
+		"file     functions  findings",
+		"main.go          1         1",
+		"total            1         1",
+	}, "\n")+"\n", out.String())
+
+	out.Reset()
+	require.Nil(t, FlushReport(reporting))
+	require.Equal(t, "no vanished code found\n", out.String())
+
+	// watch rounds without new findings print nothing, while summary counts only new findings
+	out.Reset()
+	incremental := &PrettyReporting{Out: out, Incremental: true}
+	require.Nil(t, FlushReport(incremental))
+	require.Empty(t, out.String())
+	reportExample(t, "forgotten_errcheck_bug.go", incremental)
+	require.True(t, strings.HasSuffix(out.String(), strings.Join([]string{
+		"file       functions  findings",
+		"main.go            1         1",
+		"total new          1         1",
+	}, "\n")+"\n"), out.String())
+}
+
+func TestPrettyReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &PrettyReporting{Out: out})
+		require.Equal(t, strings.Join([]string{
+			"lib/lib.go",
+			"  func Lib",
+			"    warning: code vanished from compiled binary (lib/lib.go:10:3)",
+			"    > 10 | \t\tpanic(err)",
+			"    related: lib/lib.go:8: error assigned here is never checked",
+			"    related: lib/lib.go:9: condition checks err instead of the error assigned before it",
+			"",
+			"main.go",
+			"  func Quoted",
+			"    warning: code vanished from compiled binary (main.go:10:3)",
+			"    > 10 | \t\tpanic(\"<\" + err.Error() + \"> & \\\"quoted\\\"\")",
+			"    related: main.go:8: error assigned here is never checked",
+			"    related: main.go:9: condition checks err instead of the error assigned before it",
+			"",
+			"other.go",
+			"  func Other",
+			"    warning: code vanished from compiled binary (other.go:10:3)",
+			"    > 10 | \t\tpanic(err)",
+			"    related: other.go:8: error assigned here is never checked",
+			"    related: other.go:9: condition checks err instead of the error assigned before it",
+			"",
+			"file        functions  findings",
+			"lib/lib.go          1         1",
+			"main.go             1         1",
+			"other.go            1         1",
+			"total               3         3",
+		}, "\n")+"\n", out.String())
+	})
+	t.Run("color", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &PrettyReporting{Out: out, Color: true})
+		require.Contains(t, out.String(), "\x1b[")
+		require.Contains(t, out.String(), `panic("<" + err.Error() + "> & \"quoted\"")`)
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &PrettyReporting{Out: out})
+		require.Equal(t, "no vanished code found\n", out.String())
+	})
+}
@@ -2,40 +2,148 @@ package main
 
 import (
 	"fmt"
+	"go/token"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
 )
 
 type Reporting interface{ ReportVanished(info VanishedInfo) }
 
-type LogReporting struct{}
+// FlushReporting is implemented by reportings which print findings only after the analysis is finished
+type FlushReporting interface {
+	Reporting
+	Flush() error
+}
 
-func (_ LogReporting) ReportVanished(info VanishedInfo) {
-	snippet := ""
-	f, err := os.OpenFile(info.Filename(), os.O_RDONLY, os.ModePerm)
-	if err != nil {
-		panic(err)
+// FlushReport prints findings buffered by the reporting (if it buffers them at all)
+func FlushReport(reporting Reporting) error {
+	if flush, ok := reporting.(FlushReporting); ok {
+		return flush.Flush()
 	}
-	start, end := info.StartLineOffsets()
-	_, _ = f.Seek(int64(start), 0)
-	buffer := make([]byte, end-start)
-	_, _ = f.Read(buffer)
-	snippet = string(buffer)
+	return nil
+}
+
+type LogReporting struct{}
 
+func (_ LogReporting) ReportVanished(info VanishedInfo) {
+	start, end := info.Region()
 	log.Printf(
-		"it seems like your code vanished from compiled binary: func=[%v], file=[%v], lines=[%v-%v], snippet:\n\t%v",
+		"it seems like %v: func=[%v], file=[%v], lines=[%v-%v], columns=[%v-%v], snippet:\n%v",
+		vanishedMessage(info, "your code"),
 		info.FuncName,
-		info.Filename(),
-		info.StartLine(),
-		info.EndLine(),
-		snippet,
+		start.Filename,
+		start.Line,
+		end.Line,
+		start.Column,
+		end.Column,
+		findingDetails(info),
 	)
 }
 
-type GitHubReporting struct{}
+// findingDetails returns snippet of the vanished code followed by the related positions
+func findingDetails(info VanishedInfo) string {
+	// text and carets are taken from the parsed file, but lines are numbered with the reported lines (they differ for //line directives)
+	start, _ := info.Region()
+	sourceStart, sourceEnd := info.SourceRegion()
+	shift := start.Line - sourceStart.Line
+	sourceStart.Line, sourceEnd.Line = sourceStart.Line+shift, sourceEnd.Line+shift
+	details := FormatSnippet(sourceStart, sourceEnd, info.SourceLines())
+	for _, related := range info.Related {
+		relatedStart, _ := info.RelatedRange(related)
+		details += fmt.Sprintf("\n\trelated: %v:%v: %v", relatedStart.Filename, relatedStart.Line, related.Message)
+	}
+	return details
+}
+
+// FormatSnippet prints full source lines of the region with line numbers and marker gutter,
+// single-line regions are also underlined with carets:
+//
+//	> 11 | 		panic(err)
+//	     | 		^^^^^^^^^^
+func FormatSnippet(start, end token.Position, lines []string) string {
+	width := len(strconv.Itoa(start.Line + len(lines) - 1))
+	var snippet strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			snippet.WriteString("\n")
+		}
+		_, _ = fmt.Fprintf(&snippet, "\t> %*d | %v", width, start.Line+i, line)
+	}
+	if len(lines) == 1 && start.Line == end.Line && 0 < start.Column && start.Column < end.Column && end.Column-1 <= len(lines[0]) {
+		// keep tabs of the indentation, so carets are aligned with the code
+		indent := strings.Map(func(c rune) rune {
+			if c == '\t' {
+				return c
+			}
+			return ' '
+		}, lines[0][:start.Column-1])
+		_, _ = fmt.Fprintf(&snippet, "\n\t  %*s | %v%v", width, "", indent, strings.Repeat("^", end.Column-start.Column))
+	}
+	return snippet.String()
+}
+
+// TemplatePositionsReporting reports findings in the generated files against the template source from //line directives
+type TemplatePositionsReporting struct{ Reporting }
 
-func (_ GitHubReporting) ReportVanished(info VanishedInfo) {
-	relativePath, _ := filepath.Rel(info.AnalysisPath, info.Filename())
-	fmt.Printf("::warning file=%v,line=%v,endLine=%v::%v\n", relativePath, info.StartLine(), info.EndLine(), "seems like code vanished from compiled binary")
+func (r TemplatePositionsReporting) ReportVanished(info VanishedInfo) {
+	info.TemplatePositions = true
+	r.Reporting.ReportVanished(info)
+}
+
+func (r TemplatePositionsReporting) Flush() error { return FlushReport(r.Reporting) }
+func (r TemplatePositionsReporting) ReportAssembly(analysisPath string, project []*packages.Package, assembly Assembly) {
+	ReportAssembly(r.Reporting, analysisPath, project, assembly)
+}
+
+type GitHubReporting struct {
+	Out io.Writer // os.Stdout if nil
+}
+
+// ReportVanished prints warning annotation for the finding and separate notice annotation for every related position
+func (r GitHubReporting) ReportVanished(info VanishedInfo) {
+	out := r.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	start, end := info.Region()
+	relativePath, _ := filepath.Rel(info.AnalysisPath, start.Filename)
+	// GitHub accepts columns only for single-line annotations
+	columns := ""
+	if start.Line == end.Line {
+		columns = fmt.Sprintf(",col=%v,endColumn=%v", start.Column, end.Column)
+	}
+	_, _ = fmt.Fprintf(out, "::warning file=%v,line=%v,endLine=%v%v::%v\n", relativePath, start.Line, end.Line, columns, "seems like "+vanishedMessage(info, "code"))
+	for _, related := range info.Related {
+		relatedStart, relatedEnd := info.RelatedRange(related)
+		relatedPath, _ := filepath.Rel(info.AnalysisPath, relatedStart.Filename)
+		_, _ = fmt.Fprintf(
+			out,
+			"::notice file=%v,line=%v,endLine=%v::%v (related to vanished code at %v:%v)\n",
+			relatedPath, relatedStart.Line, relatedEnd.Line, related.Message, relativePath, start.Line,
+		)
+	}
+}
+
+func vanishedMessage(info VanishedInfo, subject string) string {
+	message := subject + " vanished from compiled binary"
+	if info.FuncReason == FuncNeverCompiled {
+		message = "function was never compiled (generic function without instantiations?)"
+	} else if info.FuncReason == FuncDroppedByLinker && len(info.LinkedIn) == 0 {
+		message = fmt.Sprintf("method is unreachable from every main package (%v) and dropped by linker (stale interface implementation?)", strings.Join(info.DroppedFrom, ", "))
+	} else if info.FuncReason == FuncDroppedByLinker {
+		message = fmt.Sprintf("method is dropped by linker from %v, but present in %v", strings.Join(info.DroppedFrom, ", "), strings.Join(info.LinkedIn, ", "))
+	}
+	if info.Generated {
+		message += " (generated file)"
+	}
+	for _, fix := range info.SuggestedFixes {
+		message += " (suggested fix: " + fix.Message + ")"
+	}
+	return message
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubReporting(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	reportExample(t, "forgotten_errcheck_bug.go", GitHubReporting{Out: out})
+	require.Equal(t, strings.Join([]string{
+		"::warning file=main.go,line=11,endLine=11,col=3,endColumn=13::seems like code vanished from compiled binary",
+		"::notice file=main.go,line=8,endLine=8::error assigned here is never checked (related to vanished code at main.go:11)",
+		"::notice file=main.go,line=9,endLine=9::condition checks err instead of the error assigned before it (related to vanished code at main.go:11)",
+	}, "\n")+"\n", out.String())
+}
+
+func TestGitHubReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, GitHubReporting{Out: out})
+		var warnings []string
+		for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+			if strings.HasPrefix(line, "::warning ") {
+				warnings = append(warnings, line)
+			}
+		}
+		require.ElementsMatch(t, []string{
+			"::warning file=main.go,line=10,endLine=10,col=3,endColumn=46::seems like code vanished from compiled binary",
+			"::warning file=other.go,line=10,endLine=10,col=3,endColumn=13::seems like code vanished from compiled binary",
+			"::warning file=lib/lib.go,line=10,endLine=10,col=3,endColumn=13::seems like code vanished from compiled binary",
+		}, warnings)
+		require.Contains(t, out.String(), "::notice file=lib/lib.go,line=8,endLine=8::error assigned here is never checked (related to vanished code at lib/lib.go:10)\n")
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", GitHubReporting{Out: out})
+		require.Empty(t, out.String())
+	})
+}
+
+func TestFormatSnippet(t *testing.T) {
+	t.Run("single line", func(t *testing.T) {
+		require.Equal(
+			t,
+			"\t> 11 | \t\tpanic(err)\n\t     | \t\t^^^^^^^^^^",
+			FormatSnippet(token.Position{Line: 11, Column: 3}, token.Position{Line: 11, Column: 13}, []string{"\t\tpanic(err)"}),
+		)
+	})
+	t.Run("multiple lines", func(t *testing.T) {
+		require.Equal(
+			t,
+			"\t>  9 | \tx := f(\n\t> 10 | \t\t1,\n\t> 11 | \t)",
+			FormatSnippet(token.Position{Line: 9, Column: 2}, token.Position{Line: 11, Column: 3}, []string{"\tx := f(", "\t\t1,", "\t)"}),
+		)
+	})
+}
+
+func TestFindingDetails(t *testing.T) {
+	// snippet is numbered with the reported lines, while carets are aligned with the parsed file text
+	for _, test := range []struct {
+		name      string
+		reporting func(Reporting) Reporting
+		expected  string
+	}{
+		{name: "generated file positions", reporting: func(r Reporting) Reporting { return r }, expected: "\t> 13 | \t\tpanic(err)\n\t     | \t\t^^^^^^^^^^"},
+		{name: "template positions", reporting: func(r Reporting) Reporting { return TemplatePositionsReporting{Reporting: r} }, expected: "\t> 4 | \t\tpanic(err)\n\t    | \t\t^^^^^^^^^^"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			collect := &CollectReporting{}
+			reportExample(t, "line_directive.go", test.reporting(collect))
+			require.Len(t, collect.Vanished, 1)
+			details := strings.Split(findingDetails(collect.Vanished[0]), "\n")
+			require.Equal(t, test.expected, strings.Join(details[:2], "\n"))
+		})
+	}
+}
@@ -14,3 +14,12 @@ func (s Set) Has(value string) bool {
 	_, ok := s[value]
 	return ok
 }
+
+func (s Set) HasAny(values ...string) bool {
+	for _, value := range values {
+		if s.Has(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// ModuleSnapshot maps watched files of the module to their modification time and size
+type ModuleSnapshot map[string]fileStamp
+
+var watchedExtensions = NewSet(".go", ".c", ".h", ".s", ".pgo")
+var moduleFiles = NewSet("go.mod", "go.sum", "go.work", "go.work.sum")
+
+func TakeModuleSnapshot(dirs ...string) (ModuleSnapshot, error) {
+	snapshot := make(ModuleSnapshot)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// file can be removed while we are walking the tree
+				return nil
+			}
+			name := entry.Name()
+			if entry.IsDir() {
+				// go command ignores these directories as well
+				if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !watchedExtensions.Has(filepath.Ext(name)) && !moduleFiles.Has(name) {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			snapshot[path] = fileStamp{ModTime: info.ModTime(), Size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// Changed returns files which were added, removed or modified in the next snapshot
+func (s ModuleSnapshot) Changed(next ModuleSnapshot) []string {
+	var changed []string
+	for path, stamp := range next {
+		if previous, ok := s[path]; !ok || previous != stamp {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s {
+		if _, ok := next[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// AffectedPackages returns packages which contain changed files together with their dependents from the module
+// changes of the module files (go.mod, go.sum, ...) affect every package
+func AffectedPackages(pkgs []ModulePackage, changed []string) Set {
+	affected := make(Set)
+	changedDirs := make(Set)
+	for _, path := range changed {
+		if moduleFiles.Has(filepath.Base(path)) {
+			for _, pkg := range pkgs {
+				affected[pkg.ImportPath] = struct{}{}
+			}
+			return affected
+		}
+		changedDirs[filepath.Dir(path)] = struct{}{}
+	}
+	for _, pkg := range pkgs {
+		if changedDirs.Has(pkg.Dir) {
+			affected[pkg.ImportPath] = struct{}{}
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, dep := range pkg.Deps {
+			if _, ok := affected[dep]; ok {
+				affected[pkg.ImportPath] = struct{}{}
+				break
+			}
+		}
+	}
+	return affected
+}
+
+type watchFinding struct {
+	Key  string // SnippetKey computed when finding was reported: file content can change later
+	Info VanishedInfo
+}
+
+// Watcher keeps findings of the module packages between analysis rounds
+type Watcher struct {
+	AnalysisPath string
+	Build        BuildConfig
+	Jobs         int
+	Cache        *AssemblyCache
+	Generated    GeneratedFiles
+	Reporting    Reporting
+
+	findings map[string][]watchFinding // import path -> findings of the package
+}
+
+// Round compiles and analyzes packages affected by changed files (all packages if changed is nil)
+// and reports findings added since the previous round; findings which disappeared are logged
+func (w *Watcher) Round(changed []string) error {
+	pkgs, err := ListModulePackages(w.AnalysisPath, w.Build)
+	if err != nil {
+		return err
+	}
+	affected := make(Set)
+	if changed == nil {
+		for _, pkg := range pkgs {
+			affected[pkg.ImportPath] = struct{}{}
+		}
+	} else {
+		affected = AffectedPackages(pkgs, changed)
+	}
+	project, err := LoadPackages(w.AnalysisPath, w.Build.Patterns(), w.Build.Flags...)
+	if err != nil {
+		return err
+	}
+	funcRegistry := CreateFuncRegistry(project)
+	projectPkgs := make(map[string]*packages.Package)
+	for _, pkg := range project {
+		projectPkgs[pkg.PkgPath] = pkg
+	}
+
+	findings := make(map[string][]watchFinding)
+	for _, pkg := range pkgs {
+		if !affected.Has(pkg.ImportPath) {
+			findings[pkg.ImportPath] = w.findings[pkg.ImportPath]
+		}
+	}
+	// unchanged packages are loaded from the cache, so only affected packages are compiled again
+	_, err = AnalyzeModuleAssemblyParallel(w.AnalysisPath, w.Build, w.Jobs, w.Cache, func(importPath string, assembly Assembly) {
+		pkg, ok := projectPkgs[importPath]
+		if !ok || !affected.Has(importPath) {
+			return
+		}
+		collect := &CollectReporting{}
+		AnalyzePackageAst(w.AnalysisPath, pkg, assembly.Lines, funcRegistry, w.Generated, Govanish, collect)
+		for _, info := range collect.Vanished {
+			findings[importPath] = append(findings[importPath], watchFinding{Key: SnippetKey(info), Info: info})
+		}
+	})
+	// failed package has no assembly, so its previous findings are kept until it compiles again instead of being reported as fixed
+	failed := FailedPackages(err)
+	for importPath, buildErr := range failed {
+		if affected.Has(importPath) {
+			log.Printf("package %v failed to compile, its findings are kept from the previous round: %v", importPath, buildErr)
+			findings[importPath] = w.findings[importPath]
+		}
+	}
+	if err != nil && len(failed) == 0 {
+		log.Printf("module analysis finished with non-critical error: %v", err)
+	}
+
+	// findings with the same key are matched by the number of occurrences, so new duplicate of the known finding is reported
+	previousCounts, currentCounts := make(map[string]int), make(map[string]int)
+	for _, pkgFindings := range w.findings {
+		for _, finding := range pkgFindings {
+			previousCounts[finding.Key]++
+		}
+	}
+	for _, pkgFindings := range findings {
+		for _, finding := range pkgFindings {
+			currentCounts[finding.Key]++
+		}
+	}
+	added, removed := 0, 0
+	for _, pkgFindings := range findings {
+		for _, finding := range pkgFindings {
+			if previousCounts[finding.Key] > 0 {
+				previousCounts[finding.Key]--
+				continue
+			}
+			added++
+			w.Reporting.ReportVanished(finding.Info)
+		}
+	}
+	for _, pkgFindings := range w.findings {
+		for _, finding := range pkgFindings {
+			if currentCounts[finding.Key] > 0 {
+				currentCounts[finding.Key]--
+				continue
+			}
+			removed++
+			log.Printf(
+				"code is back in compiled binary: func=[%v], file=[%v], lines=[%v-%v]",
+				finding.Info.FuncName,
+				finding.Info.Filename(),
+				finding.Info.StartLine(),
+				finding.Info.EndLine(),
+			)
+		}
+	}
+	log.Printf("analyzed %v affected packages: %v new findings, %v findings fixed", len(affected), added, removed)
+	w.findings = findings
+	return FlushReport(w.Reporting)
+}
+
+// Watch analyzes the module and then polls its files, analyzing affected packages again after every change
+func (w *Watcher) Watch(interval time.Duration) error {
+	dirs := []string{w.AnalysisPath}
+	for _, module := range w.Build.Replaced {
+		dirs = append(dirs, module.Dir)
+	}
+	snapshot, err := TakeModuleSnapshot(dirs...)
+	if err != nil {
+		return err
+	}
+	if w.Cache == nil {
+		log.Printf("assembly cache is disabled - every package will be compiled again after each change")
+	}
+	var changed []string
+	for {
+		if err := w.Round(changed); err != nil {
+			log.Printf("analysis failed: %v", err)
+		}
+		log.Printf("watching %v files for changes", len(snapshot))
+		for {
+			time.Sleep(interval)
+			next, err := TakeModuleSnapshot(dirs...)
+			if err != nil {
+				log.Printf("unable to scan module files: %v", err)
+				continue
+			}
+			changed = snapshot.Changed(next)
+			snapshot = next
+			if len(changed) > 0 {
+				break
+			}
+		}
+		for _, path := range changed {
+			if relativePath, err := filepath.Rel(w.AnalysisPath, path); err == nil {
+				path = relativePath
+			}
+			log.Printf("changed: %v", path)
+		}
+		if _, err := os.Stat(w.AnalysisPath); err != nil {
+			return err
+		}
+	}
+}
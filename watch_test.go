@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme\n"), 0o644))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "testdata"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "testdata", "input.go"), []byte("package input\n"), 0o644))
+
+	snapshot, err := TakeModuleSnapshot(dir)
+	require.Nil(t, err)
+	require.Len(t, snapshot, 2)
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "lib"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "lib", "lib.go"), []byte("package lib\n"), 0o644))
+	require.Nil(t, os.Remove(filepath.Join(dir, "go.mod")))
+	next, err := TakeModuleSnapshot(dir)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "go.mod"),
+		filepath.Join(dir, "lib", "lib.go"),
+		filepath.Join(dir, "main.go"),
+	}, snapshot.Changed(next))
+	require.Empty(t, next.Changed(next))
+}
+
+func TestAffectedPackages(t *testing.T) {
+	pkgs := []ModulePackage{
+		{ImportPath: "example.com/m", Dir: "/m", Deps: []string{"example.com/m/api", "example.com/m/store", "fmt"}},
+		{ImportPath: "example.com/m/api", Dir: "/m/api", Deps: []string{"example.com/m/store"}},
+		{ImportPath: "example.com/m/store", Dir: "/m/store"},
+		{ImportPath: "example.com/m/util", Dir: "/m/util"},
+	}
+	require.Equal(t, NewSet("example.com/m", "example.com/m/api", "example.com/m/store"), AffectedPackages(pkgs, []string{"/m/store/store.go"}))
+	require.Equal(t, NewSet("example.com/m/util"), AffectedPackages(pkgs, []string{"/m/util/util.go"}))
+	require.Equal(t, NewSet("example.com/m", "example.com/m/api", "example.com/m/store", "example.com/m/util"), AffectedPackages(pkgs, []string{"/m/go.mod"}))
+}
+
+func TestWatcherRound(t *testing.T) {
+	dir, dispose, err := MustGenMod(`package main
+
+func NoErrCheck(w interface{ Write(n int) error }) {
+	err := w.Write(1)
+	if err != nil {
+		panic(err)
+	}
+	_ = w.Write(2)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {}`)
+	require.Nil(t, err)
+	defer dispose()
+
+	collect := &CollectReporting{}
+	watcher := &Watcher{AnalysisPath: dir, Jobs: 1, Reporting: collect}
+	require.Nil(t, watcher.Round(nil))
+	require.Len(t, collect.Vanished, 1)
+
+	// unrelated edit shifts the finding, but it must not be reported again
+	mainPath := filepath.Join(dir, "main.go")
+	src, err := os.ReadFile(mainPath)
+	require.Nil(t, err)
+	shifted := strings.Replace(string(src), "package main\n", "package main\n\n// Writer is checked for errors\n", 1)
+	require.Nil(t, os.WriteFile(mainPath, []byte(shifted), 0o644))
+	require.Nil(t, watcher.Round([]string{mainPath}))
+	require.Len(t, collect.Vanished, 1)
+	require.Len(t, watcher.findings["github.com/sivukhin/govanish/"+filepath.Base(dir)], 1)
+
+	// identical vanished check in the same function has the same key, but it is still the new finding
+	check := "\tif err != nil {\n\t\tpanic(err)\n\t}\n}"
+	duplicated := strings.Replace(shifted, check, "\tif err != nil {\n\t\tpanic(err)\n\t}\n"+check, 1)
+	require.Nil(t, os.WriteFile(mainPath, []byte(duplicated), 0o644))
+	require.Nil(t, watcher.Round([]string{mainPath}))
+	require.Len(t, collect.Vanished, 2)
+	require.Len(t, watcher.findings["github.com/sivukhin/govanish/"+filepath.Base(dir)], 2)
+
+	// findings of the package which failed to compile are neither fixed nor reported again after the build is repaired
+	require.Nil(t, os.WriteFile(mainPath, []byte(duplicated+"\nvar _ int = \"broken\"\n"), 0o644))
+	require.Nil(t, watcher.Round([]string{mainPath}))
+	require.Len(t, collect.Vanished, 2)
+	require.Len(t, watcher.findings["github.com/sivukhin/govanish/"+filepath.Base(dir)], 2)
+	require.Nil(t, os.WriteFile(mainPath, []byte(duplicated), 0o644))
+	require.Nil(t, watcher.Round([]string{mainPath}))
+	require.Len(t, collect.Vanished, 2)
+
+	fixed := strings.Replace(shifted, "_ = w.Write(2)", "err = w.Write(2)", 1)
+	require.Nil(t, os.WriteFile(mainPath, []byte(fixed), 0o644))
+	require.Nil(t, watcher.Round([]string{mainPath}))
+	require.Len(t, collect.Vanished, 2)
+	require.Empty(t, watcher.findings["github.com/sivukhin/govanish/"+filepath.Base(dir)])
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+func writeXml(out io.Writer, report any) error {
+	if out == nil {
+		out = os.Stdout
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// CheckstyleReporting writes findings in checkstyle XML format on Flush: one error per finding with related positions in the message
+type CheckstyleReporting struct {
+	Out io.Writer // os.Stdout if nil
+
+	findings []VanishedInfo
+}
+
+func (r *CheckstyleReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func (r *CheckstyleReporting) Flush() error {
+	findings := r.findings
+	r.findings = nil
+	sortByPackage(findings)
+	report := checkstyleReport{Version: "4.3"}
+	for _, info := range findings {
+		start, _ := info.Region()
+		path := reportPath(info, start.Filename)
+		message := fmt.Sprintf("func %v: seems like %v", info.FuncName, vanishedMessage(info, "code"))
+		for i, related := range info.Related {
+			relatedStart, _ := info.RelatedRange(related)
+			separator := "; "
+			if i == 0 {
+				separator = " (related: "
+			}
+			message += fmt.Sprintf("%v%v:%v: %v", separator, reportPath(info, relatedStart.Filename), relatedStart.Line, related.Message)
+		}
+		if len(info.Related) > 0 {
+			message += ")"
+		}
+		index := slices.IndexFunc(report.Files, func(file checkstyleFile) bool { return file.Name == path })
+		if index < 0 {
+			report.Files = append(report.Files, checkstyleFile{Name: path})
+			index = len(report.Files) - 1
+		}
+		report.Files[index].Errors = append(report.Files[index].Errors, checkstyleError{
+			Line:     start.Line,
+			Column:   start.Column,
+			Severity: "warning",
+			Message:  message,
+			Source:   "govanish",
+		})
+	}
+	return writeXml(r.Out, report)
+}
+
+type junitReport struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	File      string       `xml:"file,attr"`
+	Line      int          `xml:"line,attr"`
+	Failure   junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Details string `xml:",cdata"`
+}
+
+// JUnitReporting writes findings in JUnit XML format on Flush: test suite per package with failed test case per finding
+type JUnitReporting struct {
+	Out io.Writer // os.Stdout if nil
+
+	findings []VanishedInfo
+}
+
+func (r *JUnitReporting) ReportVanished(info VanishedInfo) {
+	r.findings = append(r.findings, info)
+}
+
+func (r *JUnitReporting) Flush() error {
+	findings := r.findings
+	r.findings = nil
+	sortByPackage(findings)
+	report := junitReport{Name: "govanish", Tests: len(findings), Failures: len(findings)}
+	for _, info := range findings {
+		if len(report.Suites) == 0 || report.Suites[len(report.Suites)-1].Name != info.Pkg.PkgPath {
+			report.Suites = append(report.Suites, junitTestSuite{Name: info.Pkg.PkgPath})
+		}
+		suite := &report.Suites[len(report.Suites)-1]
+		suite.Tests++
+		suite.Failures++
+		start, _ := info.Region()
+		path := reportPath(info, start.Filename)
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      fmt.Sprintf("%v %v:%v", info.FuncName, path, start.Line),
+			ClassName: info.Pkg.PkgPath,
+			File:      path,
+			Line:      start.Line,
+			Failure: junitFailure{
+				Message: "seems like " + vanishedMessage(info, "code"),
+				Type:    "vanished",
+				Details: findingDetails(info),
+			},
+		})
+	}
+	return writeXml(r.Out, report)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckstyleReporting(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	reporting := &CheckstyleReporting{Out: out}
+	reportExample(t, "forgotten_errcheck_bug.go", reporting)
+
+	var report checkstyleReport
+	require.Nil(t, xml.Unmarshal(out.Bytes(), &report))
+	// related positions are folded into the message, so every finding is counted once
+	message := "func NoErrCheck: seems like code vanished from compiled binary " +
+		"(related: main.go:8: error assigned here is never checked; main.go:9: condition checks err instead of the error assigned before it)"
+	require.Equal(t, []checkstyleFile{{Name: "main.go", Errors: []checkstyleError{
+		{Line: 11, Column: 3, Severity: "warning", Message: message, Source: "govanish"},
+	}}}, report.Files)
+}
+
+func TestJUnitReporting(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	reporting := &JUnitReporting{Out: out}
+	reportExample(t, "forgotten_errcheck_bug.go", reporting)
+
+	var report junitReport
+	require.Nil(t, xml.Unmarshal(out.Bytes(), &report))
+	require.Equal(t, 1, report.Tests)
+	require.Equal(t, 1, report.Failures)
+	require.Len(t, report.Suites, 1)
+	suite := report.Suites[0]
+	require.Equal(t, 1, suite.Failures)
+	require.Len(t, suite.Cases, 1)
+	require.Equal(t, "NoErrCheck main.go:11", suite.Cases[0].Name)
+	require.Equal(t, suite.Name, suite.Cases[0].ClassName)
+	require.Equal(t, "seems like code vanished from compiled binary", suite.Cases[0].Failure.Message)
+	require.Contains(t, suite.Cases[0].Failure.Details, "> 11 | \t\tpanic(err)")
+
+	out.Reset()
+	require.Nil(t, FlushReport(reporting))
+	require.Nil(t, xml.Unmarshal(out.Bytes(), &report))
+	require.Equal(t, 0, report.Tests)
+}
+
+func TestCheckstyleReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &CheckstyleReporting{Out: out})
+
+		var report checkstyleReport
+		require.Nil(t, xml.Unmarshal(out.Bytes(), &report))
+		var files []string
+		for _, file := range report.Files {
+			require.Len(t, file.Errors, 1)
+			files = append(files, file.Name)
+		}
+		require.Equal(t, []string{"main.go", "other.go", "lib/lib.go"}, files)
+		require.Contains(t, report.Files[2].Errors[0].Message, "(related: lib/lib.go:8: error assigned here is never checked;")
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &CheckstyleReporting{Out: out})
+		require.Equal(t, xml.Header+`<checkstyle version="4.3"></checkstyle>`+"\n", out.String())
+	})
+}
+
+func TestJUnitReportingEdgeCases(t *testing.T) {
+	t.Run("several files and packages", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &JUnitReporting{Out: out})
+
+		var report junitReport
+		require.Nil(t, xml.Unmarshal(out.Bytes(), &report))
+		require.Equal(t, 3, report.Tests)
+		require.Len(t, report.Suites, 2)
+		require.Equal(t, report.Suites[0].Name+"/lib", report.Suites[1].Name)
+		require.Equal(t, 2, report.Suites[0].Failures)
+		require.Equal(t, "Quoted main.go:10", report.Suites[0].Cases[0].Name)
+		require.Equal(t, "Other other.go:10", report.Suites[0].Cases[1].Name)
+		require.Equal(t, "Lib lib/lib.go:10", report.Suites[1].Cases[0].Name)
+	})
+	t.Run("escaping", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportEdgeCases(t, &JUnitReporting{Out: out})
+
+		// snippet is kept as is inside CDATA section
+		require.Contains(t, out.String(), `<![CDATA[	> 10 | 		panic("<" + err.Error() + "> & \"quoted\"")`)
+		var report junitReport
+		require.Nil(t, xml.Unmarshal(out.Bytes(), &report))
+		require.Contains(t, report.Suites[0].Cases[0].Failure.Details, `panic("<" + err.Error() + "> & \"quoted\"")`)
+	})
+	t.Run("no findings", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		reportExample(t, "func_usage.go", &JUnitReporting{Out: out})
+		require.Equal(t, xml.Header+`<testsuites name="govanish" tests="0" failures="0"></testsuites>`+"\n", out.String())
+	})
+}